@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// exportVersion is the version of the export format written by export. Bump it
+// when records change in a way that older versions can't read, and keep
+// importing the old versions.
+const exportVersion = 1
+
+// recordTypeGame is the type of records for scheduled games, which are the
+// only thing exported so far.
+const recordTypeGame = "game"
+
+// exportRecord is one line of an export. Every record carries the format
+// version, so exports can be concatenated or edited by hand.
+type exportRecord struct {
+	Version      int
+	Type         string
+	Channel      string
+	Date         string
+	TargetWord   string
+	FullAttempts int
+	// Shape is in the format the config uses, see srordle.ParseShape.
+	Shape []string
+}
+
+// csvHeader is the first row of CSV exports. Shapes are written as a single
+// column, with rows separated by slashes.
+var csvHeader = []string{"version", "type", "channel", "date", "target_word", "full_attempts", "shape"}
+
+type ExportCmd struct {
+	Path string `arg:"" optional:"" help:"Where to write the export. Defaults to stdout." type:"path"`
+
+	DatabasePath string `help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	Format       string `help:"The format to write, either jsonl or csv." enum:"jsonl,csv" default:"jsonl"`
+	Channel      string `help:"Only export games in this channel. Defaults to every channel."`
+}
+
+func (e *ExportCmd) Run(ctx *Context) error {
+	bdb, err := openDB(ctx, e.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	channels := []string{e.Channel}
+	if e.Channel == "" {
+		if channels, err = bdb.Channels(); err != nil {
+			return fmt.Errorf("failed to list channels: %w", err)
+		}
+	}
+
+	var (
+		out  = ctx.Out
+		file *os.File
+	)
+	if e.Path != "" && e.Path != "-" {
+		if file, err = os.Create(e.Path); err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	bw := bufio.NewWriter(out)
+	w := newRecordWriter(e.Format, bw)
+
+	count := 0
+	for _, ch := range channels {
+		games, err := bdb.ScheduledGames(ch)
+		if err != nil {
+			return fmt.Errorf("failed to load games for channel %q: %w", ch, err)
+		}
+		for _, dg := range games {
+			rec := &exportRecord{
+				Version:      exportVersion,
+				Type:         recordTypeGame,
+				Channel:      ch,
+				Date:         dg.Date.String(),
+				TargetWord:   dg.Game.TargetWord,
+				FullAttempts: dg.Game.FullAttempts,
+				Shape:        dg.Game.Shape.Strings(),
+			}
+			if err := w.Write(rec); err != nil {
+				return fmt.Errorf("failed to write record: %w", err)
+			}
+			count++
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write records: %w", err)
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if file != nil {
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to close export file: %w", err)
+		}
+	}
+
+	slog.Info("exported games", "count", count, "channels", len(channels))
+	return nil
+}
+
+type ImportCmd struct {
+	Path string `arg:"" help:"The export to import, or - for stdin." type:"path"`
+
+	DatabasePath string `help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	Format       string `help:"The format to read, either jsonl or csv." enum:"jsonl,csv" default:"jsonl"`
+	Mode         string `help:"How to import, either merge, which replaces games on the dates in the export and keeps the rest, or replace, which deletes every game first." enum:"merge,replace" default:"merge"`
+}
+
+// importedGame is a game read from an export.
+type importedGame struct {
+	Channel string
+	Date    db.Date
+	Game    *srordle.Game
+}
+
+func (i *ImportCmd) Run(ctx *Context) error {
+	var in io.Reader = os.Stdin
+	if i.Path != "-" {
+		f, err := os.Open(i.Path)
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		defer f.Close()
+		in = f
+	}
+
+	// Everything is read and checked before the database is touched, so a bad
+	// export doesn't leave a half-imported schedule behind.
+	games, err := readImport(newRecordReader(i.Format, bufio.NewReader(in)))
+	if err != nil {
+		return err
+	}
+
+	bdb, err := openDB(ctx, i.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	deleted := 0
+	if i.Mode == "replace" {
+		if deleted, err = deleteAllGames(bdb); err != nil {
+			return err
+		}
+	}
+
+	for _, g := range games {
+		if err := bdb.AddGame(g.Channel, g.Date, g.Game); err != nil {
+			return fmt.Errorf("failed to add game for %s in channel %q: %w", g.Date, g.Channel, err)
+		}
+	}
+
+	slog.Info("imported games", "mode", i.Mode, "count", len(games), "deleted", deleted)
+	return nil
+}
+
+// readImport reads and validates every record from r.
+func readImport(r recordReader) ([]importedGame, error) {
+	type channelDate struct {
+		channel string
+		date    db.Date
+	}
+	seen := make(map[channelDate]int)
+
+	var out []importedGame
+	for n := 1; ; n++ {
+		rec, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			return out, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+
+		g, err := rec.toGame()
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", n, err)
+		}
+		key := channelDate{g.Channel, g.Date}
+		if prev, ok := seen[key]; ok {
+			return nil, fmt.Errorf("record %d: %s in channel %q is already set by record %d", n, g.Date, g.Channel, prev)
+		}
+		seen[key] = n
+		out = append(out, g)
+	}
+}
+
+func (rec *exportRecord) toGame() (importedGame, error) {
+	if rec.Version < 1 || rec.Version > exportVersion {
+		return importedGame{}, fmt.Errorf("unsupported version %d, expected 1 to %d", rec.Version, exportVersion)
+	}
+	if rec.Type != recordTypeGame {
+		return importedGame{}, fmt.Errorf("unsupported type %q", rec.Type)
+	}
+	if err := db.ValidateChannel(rec.Channel); err != nil {
+		return importedGame{}, err
+	}
+	date, err := db.ParseDate(rec.Date)
+	if err != nil {
+		return importedGame{}, err
+	}
+	shape, err := srordle.ParseShape(rec.Shape)
+	if err != nil {
+		return importedGame{}, fmt.Errorf("invalid shape: %w", err)
+	}
+	g := &srordle.Game{
+		TargetWord:   rec.TargetWord,
+		Shape:        shape,
+		FullAttempts: rec.FullAttempts,
+	}
+	if err := g.Validate(); err != nil {
+		return importedGame{}, fmt.Errorf("invalid game for %s: %w", date, err)
+	}
+	return importedGame{Channel: rec.Channel, Date: date, Game: g}, nil
+}
+
+// deleteAllGames deletes every game in every channel, returning how many were
+// deleted.
+func deleteAllGames(bdb db.Store) (int, error) {
+	channels, err := bdb.Channels()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list channels: %w", err)
+	}
+	n := 0
+	for _, ch := range channels {
+		games, err := bdb.ScheduledGames(ch)
+		if err != nil {
+			return n, fmt.Errorf("failed to load games for channel %q: %w", ch, err)
+		}
+		for _, dg := range games {
+			if err := bdb.DeleteGame(ch, dg.Date); err != nil {
+				return n, fmt.Errorf("failed to delete game for %s in channel %q: %w", dg.Date, ch, err)
+			}
+			n++
+		}
+	}
+	return n, nil
+}
+
+type recordWriter interface {
+	Write(rec *exportRecord) error
+	Flush() error
+}
+
+type recordReader interface {
+	// Read returns the next record, or io.EOF when there aren't any more.
+	Read() (*exportRecord, error)
+}
+
+func newRecordWriter(format string, w io.Writer) recordWriter {
+	if format == "csv" {
+		return &csvRecordWriter{w: csv.NewWriter(w)}
+	}
+	return &jsonRecordWriter{enc: json.NewEncoder(w)}
+}
+
+func newRecordReader(format string, r io.Reader) recordReader {
+	if format == "csv" {
+		cr := csv.NewReader(r)
+		cr.FieldsPerRecord = len(csvHeader)
+		return &csvRecordReader{r: cr}
+	}
+	return &jsonRecordReader{dec: json.NewDecoder(r)}
+}
+
+type jsonRecordWriter struct {
+	enc *json.Encoder
+}
+
+func (j *jsonRecordWriter) Write(rec *exportRecord) error { return j.enc.Encode(rec) }
+func (j *jsonRecordWriter) Flush() error                  { return nil }
+
+type jsonRecordReader struct {
+	dec *json.Decoder
+}
+
+func (j *jsonRecordReader) Read() (*exportRecord, error) {
+	var rec exportRecord
+	if err := j.dec.Decode(&rec); errors.Is(err, io.EOF) {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	return &rec, nil
+}
+
+type csvRecordWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (c *csvRecordWriter) Write(rec *exportRecord) error {
+	if !c.wroteHeader {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+		c.wroteHeader = true
+	}
+	return c.w.Write([]string{
+		strconv.Itoa(rec.Version),
+		rec.Type,
+		rec.Channel,
+		rec.Date,
+		rec.TargetWord,
+		strconv.Itoa(rec.FullAttempts),
+		strings.Join(rec.Shape, "/"),
+	})
+}
+
+func (c *csvRecordWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type csvRecordReader struct {
+	r          *csv.Reader
+	readHeader bool
+}
+
+func (c *csvRecordReader) Read() (*exportRecord, error) {
+	if !c.readHeader {
+		header, err := c.r.Read()
+		if err != nil {
+			return nil, err
+		}
+		if strings.Join(header, ",") != strings.Join(csvHeader, ",") {
+			return nil, fmt.Errorf("unexpected CSV header %q, expected %q", header, csvHeader)
+		}
+		c.readHeader = true
+	}
+
+	row, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	version, err := strconv.Atoi(row[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid version %q: %w", row[0], err)
+	}
+	fullAttempts, err := strconv.Atoi(row[5])
+	if err != nil {
+		return nil, fmt.Errorf("invalid full attempts %q: %w", row[5], err)
+	}
+	return &exportRecord{
+		Version:      version,
+		Type:         row[1],
+		Channel:      row[2],
+		Date:         row[3],
+		TargetWord:   row[4],
+		FullAttempts: fullAttempts,
+		Shape:        strings.Split(row[6], "/"),
+	}, nil
+}
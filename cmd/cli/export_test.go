@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportImport(t *testing.T) {
+	dir := t.TempDir()
+	date := db.Date{Year: 2022, Month: time.August, Day: 20}
+	shape := srordle.Shape{{true, true, true, false, true, true, true}, {true, true, true, true, true, true, true}}
+
+	withDB := func(path string, fn func(*db.DB)) {
+		bdb, err := db.Open(path)
+		if err != nil {
+			t.Fatalf("db.Open: %v", err)
+		}
+		defer bdb.Close()
+		fn(bdb)
+	}
+	addGames := func(path, channel string, words ...string) {
+		withDB(path, func(bdb *db.DB) {
+			for i, w := range words {
+				if err := bdb.AddGame(channel, date.AddDays(i), &srordle.Game{TargetWord: w, Shape: shape, FullAttempts: 2}); err != nil {
+					t.Fatalf("AddGame: %v", err)
+				}
+			}
+		})
+	}
+	schedule := func(path string) []string {
+		var out []string
+		withDB(path, func(bdb *db.DB) {
+			channels, err := bdb.Channels()
+			if err != nil {
+				t.Fatalf("Channels: %v", err)
+			}
+			for _, ch := range channels {
+				games, err := bdb.ScheduledGames(ch)
+				if err != nil {
+					t.Fatalf("ScheduledGames: %v", err)
+				}
+				for _, dg := range games {
+					out = append(out, strings.Join([]string{ch, dg.Date.String(), dg.Game.TargetWord}, " "))
+				}
+			}
+		})
+		return out
+	}
+	ctx := func(out *bytes.Buffer) *Context {
+		return &Context{Config: config.Default(), Out: out}
+	}
+
+	src := filepath.Join(dir, "src")
+	addGames(src, db.DefaultChannel, "detract", "cottage")
+	addGames(src, "work", "carrots")
+	want := []string{"default 2022-08-20 detract", "default 2022-08-21 cottage", "work 2022-08-20 carrots"}
+
+	for _, format := range []string{"jsonl", "csv"} {
+		t.Run(format, func(t *testing.T) {
+			exportPath := filepath.Join(dir, "export."+format)
+			if err := (&ExportCmd{Path: exportPath, DatabasePath: src, Format: format}).Run(ctx(&bytes.Buffer{})); err != nil {
+				t.Fatalf("export to file: %v", err)
+			}
+
+			// Merging keeps games on other dates and replaces the ones in the
+			// export.
+			dst := filepath.Join(dir, "merge-"+format)
+			addGames(dst, db.DefaultChannel, "example", "example", "example")
+			if err := (&ImportCmd{Path: exportPath, DatabasePath: dst, Format: format, Mode: "merge"}).Run(ctx(&bytes.Buffer{})); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			wantMerged := []string{"default 2022-08-20 detract", "default 2022-08-21 cottage", "default 2022-08-22 example", "work 2022-08-20 carrots"}
+			if diff := cmp.Diff(wantMerged, schedule(dst)); diff != "" {
+				t.Errorf("unexpected merged schedule (-want +got)\n%s", diff)
+			}
+
+			if err := (&ImportCmd{Path: exportPath, DatabasePath: dst, Format: format, Mode: "replace"}).Run(ctx(&bytes.Buffer{})); err != nil {
+				t.Fatalf("import: %v", err)
+			}
+			if diff := cmp.Diff(want, schedule(dst)); diff != "" {
+				t.Errorf("unexpected replaced schedule (-want +got)\n%s", diff)
+			}
+		})
+	}
+
+	tests := []struct {
+		desc    string
+		in      string
+		wantErr string
+	}{
+		{
+			desc:    "future version",
+			in:      `{"Version":2,"Type":"game","Channel":"default","Date":"2022-08-20","TargetWord":"detract","FullAttempts":2,"Shape":["xxxxxxx"]}`,
+			wantErr: "record 1: unsupported version 2",
+		},
+		{
+			desc: "duplicate date",
+			in: `{"Version":1,"Type":"game","Channel":"default","Date":"2022-08-20","TargetWord":"detract","FullAttempts":2,"Shape":["xxxxxxx"]}
+{"Version":1,"Type":"game","Channel":"default","Date":"2022-08-20","TargetWord":"cottage","FullAttempts":2,"Shape":["xxxxxxx"]}`,
+			wantErr: "record 2: 2022-08-20 in channel \"default\" is already set by record 1",
+		},
+		{
+			desc:    "bad shape",
+			in:      `{"Version":1,"Type":"game","Channel":"default","Date":"2022-08-20","TargetWord":"detract","FullAttempts":2,"Shape":["xxx"]}`,
+			wantErr: "record 1: invalid shape",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			_, err := readImport(newRecordReader("jsonl", strings.NewReader(test.in)))
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("readImport returned %v, want an error containing %q", err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// defaultListDays is how many days list shows if --to isn't given.
+const defaultListDays = 30
+
+// gameOutput are the flags shared by commands that print games.
+type gameOutput struct {
+	DatabasePath string `help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	Channel      string `help:"The channel to read games from." default:"default"`
+	HideWord     bool   `help:"Don't print target words, e.g. to check the schedule without spoiling it."`
+	JSON         bool   `help:"Print JSON instead of text, for scripting."`
+}
+
+type ListCmd struct {
+	gameOutput `embed:""`
+
+	From string `help:"The first date to list, as YYYY-MM-DD. Defaults to today."`
+	To   string `help:"The last date to list, as YYYY-MM-DD. Defaults to 30 days after --from."`
+}
+
+func (l *ListCmd) Run(ctx *Context) error {
+	from := db.ToDate(time.Now())
+	if l.From != "" {
+		d, err := db.ParseDate(l.From)
+		if err != nil {
+			return fmt.Errorf("invalid --from: %w", err)
+		}
+		from = d
+	}
+	to := from.AddDays(defaultListDays - 1)
+	if l.To != "" {
+		d, err := db.ParseDate(l.To)
+		if err != nil {
+			return fmt.Errorf("invalid --to: %w", err)
+		}
+		to = d
+	}
+	if from.After(to) {
+		return fmt.Errorf("--from (%s) must not be after --to (%s)", from, to)
+	}
+
+	bdb, err := openDB(ctx, l.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	games, err := bdb.Games(l.Channel, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+
+	if l.JSON {
+		out := []printedGame{}
+		for _, dg := range games {
+			out = append(out, l.toPrinted(dg))
+		}
+		return writeJSON(ctx.Out, out)
+	}
+
+	for i, dg := range games {
+		if i > 0 {
+			fmt.Fprintln(ctx.Out)
+		}
+		l.printGame(ctx.Out, dg)
+	}
+	return nil
+}
+
+type ShowCmd struct {
+	gameOutput `embed:""`
+
+	Date string `arg:"" help:"The date of the game to show, as YYYY-MM-DD."`
+}
+
+func (s *ShowCmd) Run(ctx *Context) error {
+	date, err := db.ParseDate(s.Date)
+	if err != nil {
+		return err
+	}
+
+	bdb, err := openDB(ctx, s.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	g, err := bdb.Game(s.Channel, date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		return fmt.Errorf("no game is scheduled for %s in channel %q", date, s.Channel)
+	} else if err != nil {
+		return fmt.Errorf("failed to load game: %w", err)
+	}
+
+	dg := db.DatedGame{Date: date, Game: g}
+	if s.JSON {
+		return writeJSON(ctx.Out, s.toPrinted(dg))
+	}
+	s.printGame(ctx.Out, dg)
+	return nil
+}
+
+// printedGame is how games are printed as JSON.
+type printedGame struct {
+	Date         string
+	TargetWord   string `json:",omitempty"`
+	FullAttempts int
+	// Shape is in the format the config uses, see srordle.ParseShape.
+	Shape []string
+}
+
+func (o *gameOutput) toPrinted(dg db.DatedGame) printedGame {
+	pg := printedGame{
+		Date:         dg.Date.String(),
+		FullAttempts: dg.Game.FullAttempts,
+		Shape:        dg.Game.Shape.Strings(),
+	}
+	if !o.HideWord {
+		pg.TargetWord = dg.Game.TargetWord
+	}
+	return pg
+}
+
+// printGame prints the date, target word, and full attempts on one line,
+// followed by a drawing of the shape, e.g.
+//
+//	2022-08-20  detract  2 full attempts
+//	  xxxxxxx
+//	  xxx.xxx
+func (o *gameOutput) printGame(w io.Writer, dg db.DatedGame) {
+	word := dg.Game.TargetWord
+	if o.HideWord {
+		word = strings.Repeat("*", srordle.WordLength)
+	}
+	fmt.Fprintf(w, "%s  %s  %s\n", dg.Date, word, plural(dg.Game.FullAttempts, "full attempt"))
+	for _, row := range dg.Game.Shape.Strings() {
+		fmt.Fprintf(w, "  %s\n", row)
+	}
+}
+
+// plural returns n and the noun, pluralized with an s if n isn't 1.
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return nil
+}
+
+// openDB opens the database at path with db.driver from the config, or the
+// configured database if path is empty.
+func openDB(ctx *Context, path string) (db.Store, error) {
+	if path == "" {
+		path = ctx.Config.DB.Path()
+	}
+	bdb, err := db.OpenStore(ctx.Config.DB.Driver, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	return bdb, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestListAndShow(t *testing.T) {
+	dir := t.TempDir()
+	bdb, err := db.Open(dir)
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	shape := srordle.Shape{{true, true, true, false, true, true, true}}
+	for i, word := range []string{"detract", "cottage"} {
+		date := db.Date{Year: 2022, Month: time.August, Day: int8(20 + i)}
+		g := &srordle.Game{TargetWord: word, Shape: shape, FullAttempts: 1 + i}
+		if err := bdb.AddGame(db.DefaultChannel, date, g); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+	if err := bdb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	run := func(cmd interface{ Run(*Context) error }) string {
+		var buf bytes.Buffer
+		if err := cmd.Run(&Context{Config: &config.Config{DB: config.DB{Dir: dir}}, Out: &buf}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return buf.String()
+	}
+	out := func(o gameOutput) gameOutput {
+		o.Channel = db.DefaultChannel
+		return o
+	}
+
+	got := run(&ListCmd{gameOutput: out(gameOutput{}), From: "2022-08-19", To: "2022-08-25"})
+	want := `2022-08-20  detract  1 full attempt
+  xxx.xxx
+
+2022-08-21  cottage  2 full attempts
+  xxx.xxx
+`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected list output (-want +got)\n%s", diff)
+	}
+
+	got = run(&ShowCmd{gameOutput: out(gameOutput{HideWord: true}), Date: "2022-08-21"})
+	if strings.Contains(got, "cottage") {
+		t.Errorf("show --hide-word printed the target word:\n%s", got)
+	}
+
+	var games []printedGame
+	if err := json.Unmarshal([]byte(run(&ListCmd{gameOutput: out(gameOutput{JSON: true}), From: "2022-08-21", To: "2022-08-21"})), &games); err != nil {
+		t.Fatalf("failed to decode JSON: %v", err)
+	}
+	wantGames := []printedGame{{Date: "2022-08-21", TargetWord: "cottage", FullAttempts: 2, Shape: []string{"xxx.xxx"}}}
+	if diff := cmp.Diff(wantGames, games); diff != "" {
+		t.Errorf("unexpected JSON games (-want +got)\n%s", diff)
+	}
+}
+
+func TestOpenDBSQLite(t *testing.T) {
+	cfg := config.Default()
+	cfg.DB.Driver = db.DriverSQLite
+	cfg.DB.SQLitePath = filepath.Join(t.TempDir(), "srordle.db")
+	ctx := &Context{Config: cfg}
+
+	// Unlike Badger, the server can keep the database open while the CLI uses it.
+	server, err := openDB(ctx, "")
+	if err != nil {
+		t.Fatalf("openDB: %v", err)
+	}
+	defer server.Close()
+	cli, err := openDB(ctx, "")
+	if err != nil {
+		t.Fatalf("openDB while already open: %v", err)
+	}
+	defer cli.Close()
+
+	date := db.Date{Year: 2022, Month: time.August, Day: 20}
+	if err := cli.AddGame(db.DefaultChannel, date, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	g, err := server.Game(db.DefaultChannel, date)
+	if err != nil {
+		t.Fatalf("Game: %v", err)
+	}
+	if g.TargetWord != "detract" {
+		t.Errorf("target word = %q, want detract", g.TargetWord)
+	}
+}
@@ -2,78 +2,226 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"math/rand"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/bcspragu/srordle/config"
 	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/logging"
 	"github.com/bcspragu/srordle/srordle"
 )
 
 type Context struct {
-	Debug bool
+	Debug  bool
+	Config *config.Config
+	// Out is where commands print their results.
+	Out io.Writer
 }
 
 type PopulateCmd struct {
-	DatabasePath    string `arg:"" name:"database path" help:"Path to the BadgerDB database directory." type:"path"`
-	TargetWordsPath string `arg:"" name:"target words path" help:"Path to the wordlist to use for the game." type:"path"`
+	DatabasePath    string `arg:"" optional:"" name:"database path" help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	TargetWordsPath string `arg:"" optional:"" name:"target words path" help:"Path to the wordlist to use for the game. Defaults to the channel's target words from the config." type:"path"`
+
+	Channel string `help:"The channel to populate games for." default:"default"`
+	Start   string `help:"The first date to schedule, as YYYY-MM-DD. Defaults to the day after the last scheduled game, or yesterday if there aren't any."`
+	Days    int    `help:"How many days to schedule. Defaults to one for each target word that hasn't been used yet."`
+	Seed    int64  `help:"The seed to shuffle the target words with. Defaults to a random seed, which is logged so a run can be repeated."`
+	DryRun  bool   `help:"Print the games that would be scheduled without adding them."`
 }
 
+// Run schedules target words that haven't been used in the channel yet onto
+// dates that don't have a game yet. Existing games are never changed.
 func (p *PopulateCmd) Run(ctx *Context) error {
-	bdb, err := db.Open(p.DatabasePath)
+	ch, ok := ctx.Config.Channel(p.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q isn't in the config", p.Channel)
+	}
+	shape, err := ch.GameShape()
+	if err != nil {
+		return fmt.Errorf("invalid shape for channel %q: %w", p.Channel, err)
+	}
+	if p.Days < 0 {
+		return fmt.Errorf("--days can't be negative, was %d", p.Days)
+	}
+
+	if p.TargetWordsPath == "" {
+		p.TargetWordsPath = ch.TargetWordsPath
+	}
+
+	bdb, err := openDB(ctx, p.DatabasePath)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
 	}
 	defer bdb.Close()
 
-	f, err := os.Open(p.TargetWordsPath)
+	scheduled, err := bdb.ScheduledGames(p.Channel)
 	if err != nil {
-		return fmt.Errorf("failed to open word list: %w", err)
+		return fmt.Errorf("failed to load scheduled games: %w", err)
 	}
-	defer f.Close()
 
-	var words []string
-	sc := bufio.NewScanner(f)
-	for sc.Scan() {
-		words = append(words, sc.Text())
+	start := db.ToDate(time.Now().AddDate(0, 0, -1))
+	if p.Start != "" {
+		if start, err = db.ParseDate(p.Start); err != nil {
+			return fmt.Errorf("invalid --start: %w", err)
+		}
+	} else if last, err := bdb.LastScheduledDate(p.Channel); err == nil {
+		start = last.AddDays(1)
+	} else if !errors.Is(err, db.ErrGameNotFound) {
+		return fmt.Errorf("failed to find the last scheduled game: %w", err)
 	}
-	r := rand.New(rand.NewSource(0))
-	order := r.Perm(len(words))
 
-	dt := db.ToDate(time.Now().AddDate(0, 0, -1))
-	for _, idx := range order {
-		err = bdb.AddGame(dt, &srordle.Game{
-			TargetWord:   words[idx],
-			FullAttempts: 2,
-			Shape:        srordle.DefaultShape(),
+	words, err := loadWords(p.TargetWordsPath)
+	if err != nil {
+		return err
+	}
+
+	seed := p.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	r := rand.New(rand.NewSource(seed))
+	r.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+
+	plan := planGames(scheduled, words, start, p.Days)
+	if p.Days > 0 && len(plan) < p.Days {
+		slog.Warn("ran out of unused target words", "channel", p.Channel, "requested_days", p.Days, "scheduled_days", len(plan))
+	}
+
+	if p.DryRun {
+		for _, w := range plan {
+			fmt.Fprintf(ctx.Out, "%s  %s\n", w.Date, w.Word)
+		}
+		return nil
+	}
+
+	for _, w := range plan {
+		err = bdb.CreateGame(p.Channel, w.Date, &srordle.Game{
+			TargetWord:   w.Word,
+			FullAttempts: ch.GameFullAttempts(),
+			Shape:        shape,
 		})
 		if err != nil {
-			return fmt.Errorf("failed to create game: %w", err)
+			return fmt.Errorf("failed to create game for %s: %w", w.Date, err)
+		}
+	}
+
+	if len(plan) == 0 {
+		slog.Info("no games to populate", "channel", p.Channel, "seed", seed)
+		return nil
+	}
+	slog.Info("populated games", "channel", p.Channel, "count", len(plan), "from", plan[0].Date, "to", plan[len(plan)-1].Date, "seed", seed)
+	return nil
+}
+
+// plannedGame is a target word that populate will schedule for a date.
+type plannedGame struct {
+	Date db.Date
+	Word string
+}
+
+// planGames assigns words, in order, to dates from start onwards, skipping
+// dates that already have a game and words that have already been scheduled.
+// If days is zero, it keeps going until it runs out of words.
+func planGames(scheduled []db.DatedGame, words []string, start db.Date, days int) []plannedGame {
+	taken := make(map[db.Date]bool)
+	used := make(map[string]bool)
+	for _, dg := range scheduled {
+		taken[dg.Date] = true
+		used[dg.Game.TargetWord] = true
+	}
+
+	var out []plannedGame
+	date := start
+	for _, w := range words {
+		if used[w] {
+			continue
+		}
+		if days > 0 && len(out) == days {
+			break
 		}
-		dt = dt.AddDays(1)
+		used[w] = true
+		for taken[date] {
+			date = date.AddDays(1)
+		}
+		out = append(out, plannedGame{Date: date, Word: w})
+		date = date.AddDays(1)
 	}
+	return out
+}
 
+// loadWords returns the non-empty lines of the word list at path.
+func loadWords(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	var words []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if w := strings.TrimSpace(sc.Text()); w != "" {
+			words = append(words, w)
+		}
+	}
 	if err := sc.Err(); err != nil {
-		return fmt.Errorf("failed to scan wordlist file: %w", err)
+		return nil, fmt.Errorf("failed to scan wordlist file: %w", err)
 	}
 
 	if err := f.Close(); err != nil {
-		return fmt.Errorf("failed to close wordlist file: %w", err)
+		return nil, fmt.Errorf("failed to close wordlist file: %w", err)
 	}
-
-	return nil
+	return words, nil
 }
 
 var cli struct {
-	Debug bool `help:"Enable debug mode."`
+	Debug     bool   `help:"Enable debug mode."`
+	Config    string `help:"Path to a TOML config file, shared with the server." type:"path" env:"SRORDLE_CONFIG"`
+	LogLevel  string `help:"The minimum level to log at, one of debug, info, warn, or error. Defaults to log.level from the config."`
+	LogFormat string `help:"The format to log in, either text or json. Defaults to log.format from the config."`
 
 	Populate PopulateCmd `cmd:"" help:"Populate the database with games"`
+	Play     PlayCmd     `cmd:"" help:"Play today's game in the terminal, against a running server"`
+	List     ListCmd     `cmd:"" help:"List scheduled games"`
+	Show     ShowCmd     `cmd:"" help:"Show the game scheduled for a date"`
+	SetGame  SetGameCmd  `cmd:"" help:"Set or replace the game for a single date"`
+	Simulate SimulateCmd `cmd:"" help:"Play every game with a solver bot, to see how hard they are"`
+	Migrate  MigrateCmd  `cmd:"" help:"Upgrade data written by older versions of the database"`
+	Export   ExportCmd   `cmd:"" help:"Export scheduled games as JSON Lines or CSV"`
+	Import   ImportCmd   `cmd:"" help:"Import scheduled games from an export"`
+
+	ValidateWordlists ValidateWordlistsCmd `cmd:"" help:"Check the dictionary and target words for words that can't be used"`
 }
 
 func main() {
 	ctx := kong.Parse(&cli)
-	err := ctx.Run(&Context{Debug: cli.Debug})
+
+	cfg, err := config.Load(cli.Config)
+	ctx.FatalIfErrorf(err)
+	if cli.LogLevel != "" {
+		cfg.Log.Level = cli.LogLevel
+	}
+	if cli.LogFormat != "" {
+		cfg.Log.Format = cli.LogFormat
+	}
+	if cli.Debug {
+		cfg.Log.Level = "debug"
+	}
+	if err := cfg.Validate(); err != nil {
+		ctx.Fatalf("invalid config:\n%v", err)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+	ctx.FatalIfErrorf(err)
+	slog.SetDefault(logger)
+
+	err = ctx.Run(&Context{Debug: cli.Debug, Config: cfg, Out: os.Stdout})
 	ctx.FatalIfErrorf(err)
 }
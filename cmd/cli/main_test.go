@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPopulate(t *testing.T) {
+	dir := t.TempDir()
+	wordsPath := filepath.Join(dir, "target.txt")
+	if err := os.WriteFile(wordsPath, []byte("detract\ncottage\n\ncarrots\nexample\n"), 0600); err != nil {
+		t.Fatalf("failed to write word list: %v", err)
+	}
+	dbDir := filepath.Join(dir, "db")
+
+	// A game that's already live, which populate shouldn't touch or reuse.
+	bdb, err := db.Open(dbDir)
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	live := db.Date{Year: 2022, Month: time.August, Day: 20}
+	if err := bdb.AddGame(db.DefaultChannel, live, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape()}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	if err := bdb.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	run := func(p *PopulateCmd) string {
+		p.DatabasePath = dbDir
+		p.TargetWordsPath = wordsPath
+		p.Channel = db.DefaultChannel
+		var buf bytes.Buffer
+		if err := p.Run(&Context{Config: config.Default(), Out: &buf}); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		return buf.String()
+	}
+
+	plan := run(&PopulateCmd{Seed: 1, Days: 2, DryRun: true})
+	if n := strings.Count(plan, "\n"); n != 2 {
+		t.Errorf("dry run planned %d games, want 2:\n%s", n, plan)
+	}
+	if !strings.HasPrefix(plan, "2022-08-21  ") {
+		t.Errorf("dry run didn't start the day after the last game:\n%s", plan)
+	}
+
+	run(&PopulateCmd{Seed: 1, Days: 2})
+	// Running again continues where the last run stopped, with the last word.
+	run(&PopulateCmd{Seed: 2})
+
+	bdb, err = db.Open(dbDir)
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer bdb.Close()
+	games, err := bdb.ScheduledGames(db.DefaultChannel)
+	if err != nil {
+		t.Fatalf("ScheduledGames: %v", err)
+	}
+	var dates []string
+	words := make(map[string]bool)
+	for _, dg := range games {
+		dates = append(dates, dg.Date.String())
+		words[dg.Game.TargetWord] = true
+	}
+	if diff := cmp.Diff([]string{"2022-08-20", "2022-08-21", "2022-08-22", "2022-08-23"}, dates); diff != "" {
+		t.Errorf("unexpected dates (-want +got)\n%s", diff)
+	}
+	if len(words) != 4 {
+		t.Errorf("a target word was used more than once: %v", games)
+	}
+	if games[0].Game.TargetWord != "detract" {
+		t.Errorf("live game changed to %q", games[0].Game.TargetWord)
+	}
+	if !strings.Contains(plan, "2022-08-21  "+games[1].Game.TargetWord) {
+		t.Errorf("dry run with the same seed planned something else:\n%s", plan)
+	}
+}
+
+func TestPlanGames(t *testing.T) {
+	start := db.Date{Year: 2022, Month: time.August, Day: 20}
+	scheduled := []db.DatedGame{
+		{Date: start.AddDays(1), Game: &srordle.Game{TargetWord: "detract"}},
+	}
+	got := planGames(scheduled, []string{"detract", "cottage", "carrots", "cottage", "example"}, start, 2)
+	want := []plannedGame{
+		{Date: start, Word: "cottage"},
+		// Skips the day that already has a game.
+		{Date: start.AddDays(2), Word: "carrots"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected plan (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import "fmt"
+
+type MigrateCmd struct {
+	DatabasePath string `arg:"" optional:"" name:"database path" help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+}
+
+func (m *MigrateCmd) Run(ctx *Context) error {
+	bdb, err := openDB(ctx, m.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	results, err := bdb.Migrate()
+	// Report what finished even if a later migration failed.
+	for _, r := range results {
+		if r.AlreadyApplied {
+			fmt.Fprintf(ctx.Out, "%s: already applied\n", r.Name)
+		} else {
+			fmt.Fprintf(ctx.Out, "%s: applied, rewrote %s\n", r.Name, plural(r.Changed, "record"))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+type PlayCmd struct {
+	Server      string `help:"The URL of the Srordle server to play against." default:"http://localhost:8000"`
+	Channel     string `help:"The channel to play." default:"default"`
+	HistoryPath string `help:"Where to keep your guesses, so a game can be picked up again later. Defaults to srordle/history.json in your config directory." type:"path"`
+	NoColor     bool   `help:"Don't use ANSI colors, and show letter statuses as symbols instead."`
+}
+
+func (p *PlayCmd) Run(ctx *Context) error {
+	if p.HistoryPath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return fmt.Errorf("failed to find config directory, set --history-path: %w", err)
+		}
+		p.HistoryPath = filepath.Join(dir, "srordle", "history.json")
+	}
+
+	pl := &player{
+		api:         &apiClient{base: strings.TrimSuffix(p.Server, "/"), http: &http.Client{Timeout: 10 * time.Second}},
+		channel:     p.Channel,
+		historyPath: p.HistoryPath,
+		color:       !p.NoColor,
+		in:          bufio.NewScanner(os.Stdin),
+		out:         os.Stdout,
+		now:         time.Now,
+	}
+	return pl.play()
+}
+
+// playedGame is the local record of a game, like what the browser keeps in
+// local storage.
+type playedGame struct {
+	Guesses               []guessAnswer
+	RemainingFullAttempts int
+	// State is the server's game state token, if it has them enabled.
+	State string `json:",omitempty"`
+}
+
+type guessAnswer struct {
+	LetterAnswers []srordle.LetterAnswer
+	RequestedFull bool
+}
+
+// history is every game played, keyed by historyKey.
+type history map[string]*playedGame
+
+func historyKey(channel string, date db.Date) string {
+	return channel + ":" + date.String()
+}
+
+func loadHistory(path string) (history, error) {
+	dat, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(history), nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	h := make(history)
+	if err := json.Unmarshal(dat, &h); err != nil {
+		return nil, fmt.Errorf("failed to parse history %q: %w", path, err)
+	}
+	return h, nil
+}
+
+func (h history) save(path string) error {
+	dat, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal history: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	if err := os.WriteFile(path, dat, 0o600); err != nil {
+		return fmt.Errorf("failed to write history: %w", err)
+	}
+	return nil
+}
+
+type apiClient struct {
+	base string
+	http *http.Client
+}
+
+// do sends the request body as JSON, if it isn't nil, and decodes the JSON
+// response into resp.
+func (c *apiClient) do(method, path string, req, resp any) error {
+	var body io.Reader
+	if req != nil {
+		dat, err := json.Marshal(req)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(dat)
+	}
+	r, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if req != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(r)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s %s returned %d: %s", method, path, res.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+type player struct {
+	api         *apiClient
+	channel     string
+	historyPath string
+	color       bool
+
+	in  *bufio.Scanner
+	out io.Writer
+	now func() time.Time
+}
+
+func (pl *player) play() error {
+	now := pl.now()
+	date := db.ToDate(now)
+
+	var sr struct {
+		Game *srordle.Game
+	}
+	path := "/api/srordle/" + date.String() + "?channel=" + url.QueryEscape(pl.channel)
+	if err := pl.api.do(http.MethodGet, path, nil, &sr); err != nil {
+		return fmt.Errorf("failed to load today's game: %w", err)
+	}
+	if sr.Game == nil {
+		return errors.New("no game was returned")
+	}
+	game := sr.Game
+
+	hist, err := loadHistory(pl.historyPath)
+	if err != nil {
+		return err
+	}
+	key := historyKey(pl.channel, date)
+	pg, ok := hist[key]
+	if !ok {
+		pg = &playedGame{RemainingFullAttempts: game.FullAttempts}
+		hist[key] = pg
+	}
+
+	fmt.Fprintf(pl.out, "Srordle for %s", date)
+	if pl.channel != db.DefaultChannel {
+		fmt.Fprintf(pl.out, " (%s)", pl.channel)
+	}
+	fmt.Fprintln(pl.out)
+
+	for {
+		pl.draw(game, pg)
+		if won(pg) {
+			fmt.Fprintln(pl.out, "You've won!")
+			return nil
+		}
+		progress := pg.progress()
+		if game.Finished(progress) {
+			pl.reveal(pg)
+			return nil
+		}
+
+		rowsUsed := progress.RowsUsed()
+		length := rowLength(game, rowsUsed)
+		if length == srordle.WordLength {
+			fmt.Fprintf(pl.out, "Guess a %d-letter word: ", length)
+		} else {
+			fmt.Fprintf(pl.out, "Guess %d letters for the next row, or ! and a 7-letter word (%d left): ", length, pg.RemainingFullAttempts)
+		}
+		if !pl.in.Scan() {
+			fmt.Fprintln(pl.out)
+			return pl.in.Err()
+		}
+		guess := strings.ToLower(strings.TrimSpace(pl.in.Text()))
+		useFull := strings.HasPrefix(guess, "!")
+		guess = strings.TrimSpace(strings.TrimPrefix(guess, "!"))
+		if guess == "" {
+			continue
+		}
+		if useFull && pg.RemainingFullAttempts <= 0 {
+			fmt.Fprintln(pl.out, "You don't have any 7-letter guesses left")
+			continue
+		}
+
+		var resp struct {
+			Answer []srordle.LetterAnswer
+			Won    bool
+			State  string
+			Error  string
+		}
+		// The date picks the game, like it does for the browser, so it's the same
+		// one loaded above.
+		req := map[string]any{
+			"guess":      guess,
+			"date":       date.String(),
+			"guessIndex": rowsUsed,
+			"useFull":    useFull,
+			"state":      pg.State,
+			"channel":    pl.channel,
+		}
+		if err := pl.api.do(http.MethodPost, "/api/guess", req, &resp); err != nil {
+			return fmt.Errorf("failed to submit guess: %w", err)
+		}
+		if resp.Error != "" {
+			fmt.Fprintln(pl.out, resp.Error)
+			continue
+		}
+
+		if useFull || rowsUsed >= len(game.Shape) {
+			pg.RemainingFullAttempts--
+		}
+		pg.Guesses = append(pg.Guesses, guessAnswer{LetterAnswers: resp.Answer, RequestedFull: useFull})
+		if resp.State != "" {
+			pg.State = resp.State
+		}
+		if err := hist.save(pl.historyPath); err != nil {
+			return err
+		}
+	}
+}
+
+// reveal shows the answer to a lost game, if the server can confirm that it's
+// over.
+func (pl *player) reveal(pg *playedGame) {
+	if pg.State == "" {
+		fmt.Fprintln(pl.out, "You've lost.")
+		return
+	}
+	var resp struct {
+		TargetWord   string
+		PartOfSpeech string
+		Definition   string
+	}
+	if err := pl.api.do(http.MethodPost, "/api/reveal", map[string]string{"state": pg.State}, &resp); err != nil || resp.TargetWord == "" {
+		fmt.Fprintln(pl.out, "You've lost.")
+		return
+	}
+	fmt.Fprintf(pl.out, "You've lost, the word was %s\n", strings.ToUpper(resp.TargetWord))
+	switch {
+	case resp.Definition != "" && resp.PartOfSpeech != "":
+		fmt.Fprintf(pl.out, "%s: %s\n", resp.PartOfSpeech, resp.Definition)
+	case resp.Definition != "":
+		fmt.Fprintln(pl.out, resp.Definition)
+	}
+}
+
+func won(pg *playedGame) bool {
+	for _, g := range pg.Guesses {
+		if len(g.LetterAnswers) == 0 {
+			continue
+		}
+		all := true
+		for _, la := range g.LetterAnswers {
+			all = all && la.Status == srordle.Correct
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// progress returns the game as the server tracks it, so that the game ends by
+// the same rules. The guessed words aren't needed for that, so they're left
+// out.
+func (pg *playedGame) progress() *srordle.Progress {
+	p := &srordle.Progress{FullAttemptsLeft: pg.RemainingFullAttempts, Won: won(pg)}
+	for _, g := range pg.Guesses {
+		p.Guesses = append(p.Guesses, srordle.Guess{RequestedFull: g.RequestedFull})
+	}
+	return p
+}
+
+// rowLength returns how many letters the next guess needs, which is the whole
+// word once the shape runs out.
+func rowLength(game *srordle.Game, rowsUsed int) int {
+	if rowsUsed >= len(game.Shape) {
+		return srordle.WordLength
+	}
+	n := 0
+	for _, used := range game.Shape[rowsUsed] {
+		if used {
+			n++
+		}
+	}
+	return n
+}
+
+// ANSI escape codes for each letter status.
+const (
+	ansiReset = "\x1b[0m"
+	ansiDim   = "\x1b[2m"
+)
+
+var statusStyles = map[srordle.LetterStatus]struct {
+	ansi   string
+	symbol string
+}{
+	srordle.Correct:       {"\x1b[30;42m", "="},
+	srordle.WrongPosition: {"\x1b[30;43m", "~"},
+	srordle.NotInWord:     {"\x1b[97;100m", "x"},
+}
+
+// cell formats a single letter, which is three characters wide.
+func (pl *player) cell(letter string, status srordle.LetterStatus) string {
+	style, ok := statusStyles[status]
+	if !ok {
+		return " " + letter + " "
+	}
+	if pl.color {
+		return style.ansi + " " + letter + " " + ansiReset
+	}
+	return style.symbol + letter + " "
+}
+
+// draw prints the board, with past guesses followed by the rows of the shape
+// that haven't been used yet, and then the keyboard.
+func (pl *player) draw(game *srordle.Game, pg *playedGame) {
+	fmt.Fprintln(pl.out)
+	for _, g := range pg.Guesses {
+		var b strings.Builder
+		for _, la := range g.LetterAnswers {
+			if la.Status == srordle.PositionNotUsed {
+				b.WriteString("   ")
+				continue
+			}
+			b.WriteString(pl.cell(strings.ToUpper(la.Letter), la.Status))
+		}
+		fmt.Fprintln(pl.out, strings.TrimRight(b.String(), " "))
+	}
+	for _, row := range game.Shape[min(pg.progress().RowsUsed(), len(game.Shape)):] {
+		var b strings.Builder
+		for _, used := range row {
+			if used {
+				b.WriteString(" _ ")
+			} else {
+				b.WriteString("   ")
+			}
+		}
+		line := strings.TrimRight(b.String(), " ")
+		if pl.color {
+			line = ansiDim + line + ansiReset
+		}
+		fmt.Fprintln(pl.out, line)
+	}
+
+	fmt.Fprintln(pl.out)
+	known := keyboardState(pg.Guesses)
+	for i, row := range []string{"qwertyuiop", "asdfghjkl", "zxcvbnm"} {
+		var b strings.Builder
+		b.WriteString(strings.Repeat(" ", i))
+		for _, key := range row {
+			b.WriteString(pl.cell(strings.ToUpper(string(key)), known[key]))
+		}
+		fmt.Fprintln(pl.out, strings.TrimRight(b.String(), " "))
+	}
+	fmt.Fprintln(pl.out)
+}
+
+// keyboardState returns the best known status of each letter that's been
+// guessed, the same way the browser's keyboard does: correct beats the wrong
+// position, which beats not in the word.
+func keyboardState(guesses []guessAnswer) map[rune]srordle.LetterStatus {
+	rank := map[srordle.LetterStatus]int{
+		srordle.NotInWord:     1,
+		srordle.WrongPosition: 2,
+		srordle.Correct:       3,
+	}
+	out := make(map[rune]srordle.LetterStatus)
+	for _, g := range guesses {
+		for _, la := range g.LetterAnswers {
+			if la.Letter == "" || la.Status == srordle.PositionNotUsed {
+				continue
+			}
+			r := []rune(la.Letter)[0]
+			if rank[la.Status] > rank[out[r]] {
+				out[r] = la.Status
+			}
+		}
+	}
+	return out
+}
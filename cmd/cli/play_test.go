@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPlay(t *testing.T) {
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	game := &srordle.Game{
+		TargetWord:   "detract",
+		Shape:        srordle.Shape{{true, true, true, false, true, true, true}},
+		FullAttempts: 1,
+	}
+
+	var guesses []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/srordle/2022-08-20", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"Game": &srordle.Game{Shape: game.Shape, FullAttempts: game.FullAttempts}})
+	})
+	mux.HandleFunc("/api/guess", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Guess      string
+			GuessIndex int
+			UseFull    bool
+			Date       string
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("failed to decode guess: %v", err)
+			return
+		}
+		if req.Date != "2022-08-20" {
+			t.Errorf("guess was for %q, want the date that was loaded, 2022-08-20", req.Date)
+		}
+		guesses = append(guesses, req.Guess)
+
+		row := srordle.Row{true, true, true, true, true, true, true}
+		if !req.UseFull {
+			row = game.Shape[req.GuessIndex]
+		}
+		split, ok := row.SplitGuess(req.Guess)
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]string{"Error": "Your guess wasn't the right shape"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"Answer": game.CalcAnswer(split, row),
+			"Won":    req.Guess == game.TargetWord,
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	histPath := filepath.Join(t.TempDir(), "history.json")
+	play := func(input string) string {
+		var out strings.Builder
+		pl := &player{
+			api:         &apiClient{base: srv.URL, http: srv.Client()},
+			channel:     "default",
+			historyPath: histPath,
+			in:          bufio.NewScanner(strings.NewReader(input)),
+			out:         &out,
+			now:         func() time.Time { return now },
+		}
+		if err := pl.play(); err != nil {
+			t.Fatalf("play: %v", err)
+		}
+		return out.String()
+	}
+
+	// A guess of the wrong shape is rejected, and doesn't use up the row.
+	out := play("cat\ncotage\n!detract\n")
+	if diff := cmp.Diff([]string{"cat", "cotage", "detract"}, guesses); diff != "" {
+		t.Errorf("unexpected guesses sent (-want +got)\n%s", diff)
+	}
+	for _, want := range []string{"Your guess wasn't the right shape", "=D =E =T", "You've won!"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, out)
+		}
+	}
+
+	hist, err := loadHistory(histPath)
+	if err != nil {
+		t.Fatalf("loadHistory: %v", err)
+	}
+	pg := hist["default:2022-08-20"]
+	if pg == nil || len(pg.Guesses) != 2 || pg.RemainingFullAttempts != 0 {
+		t.Fatalf("unexpected history %+v", pg)
+	}
+
+	// Playing again picks up where we left off, without asking for guesses.
+	if out := play(""); !strings.Contains(out, "You've won!") {
+		t.Errorf("replaying a finished game didn't show it as won:\n%s", out)
+	}
+	if len(guesses) != 3 {
+		t.Errorf("replaying a finished game sent %d more guesses", len(guesses)-3)
+	}
+
+	// Without full attempts, every row of the shape can still be guessed, and
+	// the game ends after the last one.
+	game.FullAttempts = 0
+	guesses = nil
+	histPath = filepath.Join(t.TempDir(), "history.json")
+	out = play("cotage\n")
+	if diff := cmp.Diff([]string{"cotage"}, guesses); diff != "" {
+		t.Errorf("unexpected guesses sent without full attempts (-want +got)\n%s", diff)
+	}
+	if !strings.Contains(out, "You've lost.") {
+		t.Errorf("output doesn't show the game as lost after the last row:\n%s", out)
+	}
+}
+
+func TestKeyboardState(t *testing.T) {
+	guesses := []guessAnswer{
+		{LetterAnswers: []srordle.LetterAnswer{
+			{Letter: "c", Status: srordle.NotInWord},
+			{Letter: "a", Status: srordle.WrongPosition},
+			{Letter: "", Status: srordle.PositionNotUsed},
+		}},
+		{LetterAnswers: []srordle.LetterAnswer{
+			{Letter: "a", Status: srordle.Correct},
+			{Letter: "c", Status: srordle.NotInWord},
+			{Letter: "t", Status: srordle.WrongPosition},
+		}},
+	}
+	want := map[rune]srordle.LetterStatus{
+		'a': srordle.Correct,
+		'c': srordle.NotInWord,
+		't': srordle.WrongPosition,
+	}
+	if diff := cmp.Diff(want, keyboardState(guesses)); diff != "" {
+		t.Errorf("unexpected keyboard state (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+)
+
+type SetGameCmd struct {
+	Date string `arg:"" help:"The date of the game to set, as YYYY-MM-DD."`
+
+	DatabasePath   string `help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	DictionaryPath string `help:"Path to the dictionary to check the word against. Defaults to the channel's dictionary from the config." type:"path"`
+	Channel        string `help:"The channel to set the game in." default:"default"`
+
+	Word         string      `help:"The target word." required:""`
+	Shape        []string    `help:"The rows of the shape, comma separated, like xxxxxxx,xxx.xxx. Defaults to the existing game's shape, or the channel's for a new game."`
+	FullAttempts optionalInt `placeholder:"INT" help:"The number of full attempts. Defaults to the existing game's, or the channel's for a new game."`
+	Force        bool        `help:"Change the game even if its date has already started somewhere in the world."`
+}
+
+func (s *SetGameCmd) Run(ctx *Context) error {
+	return s.setGame(ctx, time.Now(), changedBy())
+}
+
+func (s *SetGameCmd) setGame(ctx *Context, now time.Time, by string) error {
+	date, err := db.ParseDate(s.Date)
+	if err != nil {
+		return err
+	}
+	ch, ok := ctx.Config.Channel(s.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q isn't in the config", s.Channel)
+	}
+	if s.DictionaryPath == "" {
+		s.DictionaryPath = ch.DictionaryPath
+	}
+
+	started := hasStarted(date, now)
+	if started && !s.Force {
+		return fmt.Errorf("%s has already started in some timezones, pass --force to change it anyway", date)
+	}
+
+	bdb, err := openDB(ctx, s.DatabasePath)
+	if err != nil {
+		return err
+	}
+	defer bdb.Close()
+
+	old, err := bdb.Game(s.Channel, date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		old = nil
+	} else if err != nil {
+		return fmt.Errorf("failed to load existing game: %w", err)
+	}
+
+	g := &srordle.Game{TargetWord: strings.ToLower(s.Word)}
+	if old != nil {
+		g.Shape, g.FullAttempts = old.Shape, old.FullAttempts
+	} else {
+		if g.Shape, err = ch.GameShape(); err != nil {
+			return fmt.Errorf("invalid shape for channel %q: %w", s.Channel, err)
+		}
+		g.FullAttempts = ch.GameFullAttempts()
+	}
+	if len(s.Shape) > 0 {
+		if g.Shape, err = srordle.ParseShape(s.Shape); err != nil {
+			return fmt.Errorf("invalid --shape: %w", err)
+		}
+	}
+	if s.FullAttempts.set {
+		g.FullAttempts = s.FullAttempts.value
+	}
+
+	dict, err := loadDictionary(s.DictionaryPath)
+	if err != nil {
+		return err
+	}
+	if err := g.CheckPlayable(dict); err != nil {
+		return err
+	}
+
+	err = bdb.ChangeGame(&db.GameChange{
+		Channel:   s.Channel,
+		Date:      date,
+		Old:       old,
+		New:       g,
+		Forced:    started,
+		ChangedBy: by,
+		ChangedAt: now,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save game: %w", err)
+	}
+
+	slog.Info("set game", "channel", s.Channel, "date", date, "game", g, "replaced", old != nil, "forced", started)
+	return nil
+}
+
+// loadDictionary loads the dictionary at dictPath.
+func loadDictionary(dictPath string) (*trie.Trie, error) {
+	f, err := os.Open(dictPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dictionary: %w", err)
+	}
+	defer f.Close()
+	dict, err := trie.New(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load dictionary: %w", err)
+	}
+	return dict, nil
+}
+
+// hasStarted reports whether the date has started anywhere in the world at the
+// given time. The first place it starts is UTC+14.
+func hasStarted(date db.Date, now time.Time) bool {
+	return !date.After(db.ToDate(now.In(time.FixedZone("UTC+14", 14*60*60))))
+}
+
+// changedBy returns who to record as making a change, which is the current
+// user, if it can be found.
+func changedBy() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// optionalInt is an integer flag that tracks whether it was given, for flags
+// where zero is a valid value but isn't the default.
+type optionalInt struct {
+	value int
+	set   bool
+}
+
+func (o *optionalInt) Decode(ctx *kong.DecodeContext) error {
+	var s string
+	if err := ctx.Scan.PopValueInto("int", &s); err != nil {
+		return err
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("expected an integer but got %q", s)
+	}
+	o.value, o.set = v, true
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSetGame(t *testing.T) {
+	dir := t.TempDir()
+	dictPath := filepath.Join(dir, "dict.txt")
+	if err := os.WriteFile(dictPath, []byte("cottage\ndetract\nhello\n"), 0644); err != nil {
+		t.Fatalf("failed to write dictionary: %v", err)
+	}
+	dbDir := filepath.Join(dir, "db")
+
+	// It's noon on the 20th in UTC, which is already the 21st in UTC+14.
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	date := db.Date{Year: 2022, Month: time.August, Day: 22}
+	started := date.AddDays(-1)
+	// Each change is a second after the last one, so they're listed in order.
+	changedAt := now
+	setGame := func(s SetGameCmd) error {
+		s.DatabasePath = dbDir
+		s.DictionaryPath = dictPath
+		s.Channel = db.DefaultChannel
+		err := s.setGame(&Context{Config: config.Default(), Out: &bytes.Buffer{}}, changedAt, "srini")
+		if err == nil {
+			changedAt = changedAt.Add(time.Second)
+		}
+		return err
+	}
+
+	if err := setGame(SetGameCmd{Date: date.String(), Word: "Detract"}); err != nil {
+		t.Fatalf("set-game: %v", err)
+	}
+	// Replacing it keeps the shape unless a new one is given.
+	if err := setGame(SetGameCmd{Date: date.String(), Word: "cottage", FullAttempts: optionalInt{value: 0, set: true}}); err != nil {
+		t.Fatalf("set-game: %v", err)
+	}
+
+	errTests := []struct {
+		desc    string
+		cmd     SetGameCmd
+		wantErr string
+	}{
+		{"not in dictionary", SetGameCmd{Date: date.String(), Word: "carrots"}, "isn't in the dictionary"},
+		{"wrong length", SetGameCmd{Date: date.String(), Word: "hello"}, "must be 7 letters"},
+		{"bad shape", SetGameCmd{Date: date.String(), Word: "detract", Shape: []string{"xxx.xx"}}, "invalid --shape"},
+		{"already started", SetGameCmd{Date: started.String(), Word: "detract"}, "pass --force"},
+	}
+	for _, test := range errTests {
+		if err := setGame(test.cmd); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("%s: set-game returned %v, want an error containing %q", test.desc, err, test.wantErr)
+		}
+	}
+
+	shape := srordle.Shape{{true, true, true, true, true, true, true}}
+	if err := setGame(SetGameCmd{Date: started.String(), Word: "detract", Shape: []string{"xxxxxxx"}, Force: true}); err != nil {
+		t.Fatalf("set-game --force: %v", err)
+	}
+
+	bdb, err := db.Open(dbDir)
+	if err != nil {
+		t.Fatalf("db.Open: %v", err)
+	}
+	defer bdb.Close()
+
+	g, err := bdb.Game(db.DefaultChannel, date)
+	if err != nil {
+		t.Fatalf("Game: %v", err)
+	}
+	want := &srordle.Game{TargetWord: "cottage", Shape: srordle.DefaultShape(), FullAttempts: 0}
+	if diff := cmp.Diff(want, g); diff != "" {
+		t.Errorf("unexpected game (-want +got)\n%s", diff)
+	}
+
+	changes, err := bdb.GameChanges()
+	if err != nil {
+		t.Fatalf("GameChanges: %v", err)
+	}
+	first := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: srordle.DefaultFullAttempts}
+	wantChanges := []*db.GameChange{
+		{Channel: db.DefaultChannel, Date: date, New: first, ChangedBy: "srini", ChangedAt: now},
+		{Channel: db.DefaultChannel, Date: date, Old: first, New: want, ChangedBy: "srini", ChangedAt: now.Add(time.Second)},
+		{Channel: db.DefaultChannel, Date: started, New: &srordle.Game{TargetWord: "detract", Shape: shape, FullAttempts: srordle.DefaultFullAttempts}, Forced: true, ChangedBy: "srini", ChangedAt: now.Add(2 * time.Second)},
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("unexpected game changes (-want +got)\n%s", diff)
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/solver"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+type SimulateCmd struct {
+	DatabasePath    string `help:"Path to the database, a directory for Badger or a file for SQLite. Defaults to the one for db.driver from the config." type:"path"`
+	DictionaryPath  string `help:"Path to the dictionary the solver guesses from. Defaults to the channel's dictionary from the config." type:"path"`
+	TargetWordsPath string `help:"Path to the target words, which the solver knows the answer is one of. Defaults to the channel's target words from the config." type:"path"`
+	Channel         string `help:"The channel to simulate." default:"default"`
+
+	TargetWords  bool        `help:"Simulate every target word with --shape and --full-attempts, instead of the scheduled games."`
+	Shape        []string    `help:"The rows of the shape to use with --target-words, comma separated, like xxxxxxx,xxx.xxx. Defaults to the channel's shape."`
+	FullAttempts optionalInt `placeholder:"INT" help:"The number of full attempts to use with --target-words. Defaults to the channel's."`
+	Worst        int         `help:"How many of the hardest games to list." default:"10"`
+	JSON         bool        `help:"Print JSON instead of text, for scripting."`
+}
+
+// simulatedGame is how the solver did on one game.
+type simulatedGame struct {
+	Date         string `json:",omitempty"`
+	TargetWord   string
+	Won          bool
+	Rows         int
+	FullAttempts int
+}
+
+// skippedGame is a game the solver couldn't play because it isn't valid.
+type skippedGame struct {
+	Date       string `json:",omitempty"`
+	TargetWord string
+	Reason     string
+}
+
+func (g simulatedGame) guesses() int {
+	return g.Rows + g.FullAttempts
+}
+
+type histogramBucket struct {
+	Guesses int
+	Games   int
+}
+
+// simulationReport summarizes how the solver did across every game.
+type simulationReport struct {
+	Games int
+	Won   int
+	// MeanRows and MeanFullAttempts are averaged over every game, won or not.
+	MeanRows         float64
+	MeanFullAttempts float64
+	// Histogram counts the games that were won by how many guesses they took.
+	Histogram []histogramBucket
+	// Worst are the hardest games, lost ones first, then the ones that needed
+	// the most full attempts and guesses.
+	Worst []simulatedGame
+	// Skipped are the games that weren't simulated because they aren't valid,
+	// like target words that don't fit the shape.
+	Skipped []skippedGame `json:",omitempty"`
+}
+
+func (s *SimulateCmd) Run(ctx *Context) error {
+	ch, ok := ctx.Config.Channel(s.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q isn't in the config", s.Channel)
+	}
+	if s.DictionaryPath == "" {
+		s.DictionaryPath = ch.DictionaryPath
+	}
+	if s.TargetWordsPath == "" {
+		s.TargetWordsPath = ch.TargetWordsPath
+	}
+
+	dict, err := loadDictionary(s.DictionaryPath)
+	if err != nil {
+		return err
+	}
+	words, err := loadWords(s.TargetWordsPath)
+	if err != nil {
+		return err
+	}
+
+	var games []db.DatedGame
+	if s.TargetWords {
+		shape, err := ch.GameShape()
+		if err != nil {
+			return fmt.Errorf("invalid shape for channel %q: %w", s.Channel, err)
+		}
+		if len(s.Shape) > 0 {
+			if shape, err = srordle.ParseShape(s.Shape); err != nil {
+				return fmt.Errorf("invalid --shape: %w", err)
+			}
+		}
+		fullAttempts := ch.GameFullAttempts()
+		if s.FullAttempts.set {
+			fullAttempts = s.FullAttempts.value
+		}
+		for _, w := range words {
+			games = append(games, db.DatedGame{Game: &srordle.Game{TargetWord: w, Shape: shape, FullAttempts: fullAttempts}})
+		}
+	} else {
+		bdb, err := openDB(ctx, s.DatabasePath)
+		if err != nil {
+			return err
+		}
+		games, err = bdb.ScheduledGames(s.Channel)
+		bdb.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load scheduled games: %w", err)
+		}
+		// Games set by hand might not be in the target words, but players could
+		// still be asked to find them.
+		known := make(map[string]bool)
+		for _, w := range words {
+			known[w] = true
+		}
+		for _, dg := range games {
+			if !known[dg.Game.TargetWord] {
+				known[dg.Game.TargetWord] = true
+				words = append(words, dg.Game.TargetWord)
+			}
+		}
+	}
+	if len(games) == 0 {
+		return fmt.Errorf("no games to simulate in channel %q", s.Channel)
+	}
+
+	sv := solver.New(dict, words)
+	var (
+		results []simulatedGame
+		skipped []skippedGame
+	)
+	for _, dg := range games {
+		if err := dg.Game.Validate(); err != nil {
+			sk := skippedGame{TargetWord: dg.Game.TargetWord, Reason: err.Error()}
+			if !s.TargetWords {
+				sk.Date = dg.Date.String()
+			}
+			skipped = append(skipped, sk)
+			continue
+		}
+		res := sv.Play(dg.Game.Clone())
+		sg := simulatedGame{
+			TargetWord:   dg.Game.TargetWord,
+			Won:          res.Won,
+			Rows:         res.RowsUsed,
+			FullAttempts: res.FullAttemptsUsed,
+		}
+		if !s.TargetWords {
+			sg.Date = dg.Date.String()
+		}
+		results = append(results, sg)
+	}
+
+	if len(results) == 0 {
+		return fmt.Errorf("none of the games to simulate in channel %q are valid, %q: %s", s.Channel, skipped[0].TargetWord, skipped[0].Reason)
+	}
+
+	report := summarize(results, s.Worst)
+	report.Skipped = skipped
+	if s.JSON {
+		return writeJSON(ctx.Out, report)
+	}
+	report.print(ctx.Out)
+	return nil
+}
+
+func summarize(results []simulatedGame, worst int) *simulationReport {
+	r := &simulationReport{Games: len(results)}
+	counts := make(map[int]int)
+	rows, full := 0, 0
+	for _, g := range results {
+		rows += g.Rows
+		full += g.FullAttempts
+		if g.Won {
+			r.Won++
+			counts[g.guesses()]++
+		}
+	}
+	r.MeanRows = float64(rows) / float64(len(results))
+	r.MeanFullAttempts = float64(full) / float64(len(results))
+
+	for n, games := range counts {
+		r.Histogram = append(r.Histogram, histogramBucket{Guesses: n, Games: games})
+	}
+	sort.Slice(r.Histogram, func(i, j int) bool { return r.Histogram[i].Guesses < r.Histogram[j].Guesses })
+
+	sorted := append([]simulatedGame(nil), results...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Won != b.Won {
+			return !a.Won
+		}
+		if a.FullAttempts != b.FullAttempts {
+			return a.FullAttempts > b.FullAttempts
+		}
+		return a.guesses() > b.guesses()
+	})
+	r.Worst = sorted[:max(0, min(worst, len(sorted)))]
+	return r
+}
+
+// histogramWidth is the length of the longest bar in the histogram.
+const histogramWidth = 40
+
+func (r *simulationReport) print(w io.Writer) {
+	fmt.Fprintf(w, "Simulated %d games, won %d (%.1f%%)\n", r.Games, r.Won, 100*float64(r.Won)/float64(r.Games))
+	fmt.Fprintf(w, "Mean rows used: %.2f\n", r.MeanRows)
+	fmt.Fprintf(w, "Mean full attempts used: %.2f\n", r.MeanFullAttempts)
+
+	if len(r.Histogram) > 0 {
+		most := 0
+		for _, b := range r.Histogram {
+			most = max(most, b.Games)
+		}
+		fmt.Fprintln(w, "\nGuesses to win:")
+		for _, b := range r.Histogram {
+			bar := strings.Repeat("#", max(1, b.Games*histogramWidth/most))
+			fmt.Fprintf(w, "%4d | %-*s %d\n", b.Guesses, histogramWidth, bar, b.Games)
+		}
+	}
+
+	if len(r.Worst) > 0 {
+		fmt.Fprintln(w, "\nHardest games:")
+		for _, g := range r.Worst {
+			outcome := "won"
+			if !g.Won {
+				outcome = "lost"
+			}
+			if g.Date != "" {
+				fmt.Fprintf(w, "  %s  ", g.Date)
+			} else {
+				fmt.Fprint(w, "  ")
+			}
+			fmt.Fprintf(w, "%s  %s, %s, %s\n", g.TargetWord, outcome, plural(g.Rows, "row"), plural(g.FullAttempts, "full attempt"))
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		fmt.Fprintf(w, "\nSkipped %s:\n", plural(len(r.Skipped), "invalid game"))
+		for _, g := range r.Skipped {
+			if g.Date != "" {
+				fmt.Fprintf(w, "  %s  ", g.Date)
+			} else {
+				fmt.Fprint(w, "  ")
+			}
+			fmt.Fprintf(w, "%s  %s\n", g.TargetWord, g.Reason)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSummarize(t *testing.T) {
+	results := []simulatedGame{
+		{TargetWord: "detract", Won: true, Rows: 2, FullAttempts: 1},
+		{TargetWord: "cottage", Won: false, Rows: 2, FullAttempts: 2},
+		{TargetWord: "carrots", Won: true, Rows: 1, FullAttempts: 2},
+		{TargetWord: "example", Won: true, Rows: 1, FullAttempts: 0},
+	}
+	got := summarize(results, 3)
+	want := &simulationReport{
+		Games:            4,
+		Won:              3,
+		MeanRows:         1.5,
+		MeanFullAttempts: 1.25,
+		Histogram:        []histogramBucket{{Guesses: 1, Games: 1}, {Guesses: 3, Games: 2}},
+		Worst:            []simulatedGame{results[1], results[2], results[0]},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected report (-want +got)\n%s", diff)
+	}
+}
+
+func TestSimulateTargetWords(t *testing.T) {
+	dir := t.TempDir()
+	words := []string{"detract", "cottage", "carrots"}
+	var dict []string
+	for _, w := range words {
+		dict = append(dict, w, w[:3], w[4:])
+	}
+	write := func(name string, lines []string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	var buf bytes.Buffer
+	cmd := &SimulateCmd{
+		DictionaryPath:  write("dict.txt", dict),
+		TargetWordsPath: write("target.txt", append(words, "carrot")),
+		Channel:         "default",
+		TargetWords:     true,
+		Shape:           []string{"xxx.xxx"},
+		FullAttempts:    optionalInt{value: 1, set: true},
+		Worst:           1,
+	}
+	if err := cmd.Run(&Context{Config: config.Default(), Out: &buf}); err != nil {
+		t.Fatalf("simulate: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "Simulated 3 games, won 3 (100.0%)\n") {
+		t.Errorf("unexpected simulation output:\n%s", out)
+	}
+	// Target words that don't fit the shape are reported instead of played.
+	if !strings.Contains(out, "Skipped 1 invalid game:\n  carrot  ") {
+		t.Errorf("simulation output didn't report the invalid target word:\n%s", out)
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+)
+
+type ValidateWordlistsCmd struct {
+	Channel         string `help:"The channel whose word lists to check." default:"default"`
+	DictionaryPath  string `help:"Path to the dictionary. Defaults to the channel's dictionary from the config." type:"path"`
+	TargetWordsPath string `help:"Path to the target words. Defaults to the channel's target words from the config." type:"path"`
+
+	Fix bool `help:"Rewrite the word lists without the problems found. Target words missing from the dictionary are removed, not added to the dictionary."`
+}
+
+// wordProblem is a line of a word list that can't be used.
+type wordProblem struct {
+	Path    string
+	Line    int
+	Word    string
+	Problem string
+}
+
+func (p wordProblem) String() string {
+	return fmt.Sprintf("%s:%d: %q %s", p.Path, p.Line, p.Word, p.Problem)
+}
+
+// wordList is a word list split into the words that can be used and the lines
+// that can't.
+type wordList struct {
+	path     string
+	words    []string
+	problems []wordProblem
+}
+
+func (v *ValidateWordlistsCmd) Run(ctx *Context) error {
+	ch, ok := ctx.Config.Channel(v.Channel)
+	if !ok {
+		return fmt.Errorf("channel %q isn't in the config", v.Channel)
+	}
+	if v.DictionaryPath == "" {
+		v.DictionaryPath = ch.DictionaryPath
+	}
+	if v.TargetWordsPath == "" {
+		v.TargetWordsPath = ch.TargetWordsPath
+	}
+
+	dictList, err := readWordList(v.DictionaryPath, nil)
+	if err != nil {
+		return err
+	}
+	dict, err := trie.New(strings.NewReader(strings.Join(dictList.words, "\n")))
+	if err != nil {
+		return fmt.Errorf("failed to load dictionary: %w", err)
+	}
+
+	targetList, err := readWordList(v.TargetWordsPath, func(w string) string {
+		if n := utf8.RuneCountInString(w); n != srordle.WordLength {
+			return fmt.Sprintf("is %d letters, expected %d", n, srordle.WordLength)
+		}
+		if ok, err := dict.HasWord(w); err != nil || !ok {
+			return "isn't in the dictionary"
+		}
+		return ""
+	})
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, wl := range []*wordList{dictList, targetList} {
+		for _, p := range wl.problems {
+			fmt.Fprintln(ctx.Out, p)
+		}
+		total += len(wl.problems)
+	}
+	if total == 0 {
+		slog.Info("word lists are valid", "dictionary", dictList.path, "target_words", targetList.path)
+		return nil
+	}
+	if !v.Fix {
+		return fmt.Errorf("found %d problems in the word lists, run with --fix to remove them", total)
+	}
+
+	for _, wl := range []*wordList{dictList, targetList} {
+		if len(wl.problems) == 0 {
+			continue
+		}
+		if err := writeWordList(wl.path, wl.words); err != nil {
+			return err
+		}
+		slog.Info("fixed word list", "path", wl.path, "removed", len(wl.problems), "words", len(wl.words))
+	}
+	return nil
+}
+
+// readWordList reads the word list at path, keeping the lines that are
+// lowercase ASCII words, haven't been seen before, and pass check, if given.
+// check returns why a word can't be used, or an empty string if it can.
+func readWordList(path string, check func(string) string) (*wordList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open word list: %w", err)
+	}
+	defer f.Close()
+
+	wl, err := checkWordList(path, f, check)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close word list: %w", err)
+	}
+	return wl, nil
+}
+
+func checkWordList(path string, r io.Reader, check func(string) string) (*wordList, error) {
+	wl := &wordList{path: path}
+	seen := make(map[string]int)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		w := sc.Text()
+		problem := ""
+		if err := trie.CheckWord(w); err != nil {
+			problem = "isn't a lowercase ASCII word"
+		} else if prev, ok := seen[w]; ok {
+			problem = fmt.Sprintf("is a duplicate of line %d", prev)
+		} else if check != nil {
+			problem = check(w)
+		}
+		if problem != "" {
+			wl.problems = append(wl.problems, wordProblem{Path: path, Line: line, Word: w, Problem: problem})
+			continue
+		}
+		seen[w] = line
+		wl.words = append(wl.words, w)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan word list: %w", err)
+	}
+	return wl, nil
+}
+
+// writeWordList replaces the word list at path with the given words, one per
+// line. It writes to a temporary file first, so the word list is never left
+// half written.
+func writeWordList(path string, words []string) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat word list: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(f.Name()) // Cleans up on failure, fails harmlessly after the rename.
+	if err := f.Chmod(fi.Mode().Perm()); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to set word list permissions: %w", err)
+	}
+
+	w := bufio.NewWriter(f)
+	for _, word := range words {
+		w.WriteString(word)
+		w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write word list: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close word list: %w", err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace word list: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateWordlists(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, contents string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	dictPath := write("dict.txt", "cottage\ndetract\nDetroit\ncottage\nhello\ncafé\n")
+	targetPath := write("target.txt", "detract\nhello\ncarrots\ndetract\nCottage\ncottage\n")
+
+	run := func(fix bool) (string, error) {
+		var buf bytes.Buffer
+		cmd := &ValidateWordlistsCmd{Channel: "default", DictionaryPath: dictPath, TargetWordsPath: targetPath, Fix: fix}
+		err := cmd.Run(&Context{Config: config.Default(), Out: &buf})
+		return buf.String(), err
+	}
+
+	out, err := run(false)
+	if err == nil {
+		t.Error("validating bad word lists didn't return an error")
+	}
+	want := []string{
+		dictPath + `:3: "Detroit" isn't a lowercase ASCII word`,
+		dictPath + `:4: "cottage" is a duplicate of line 1`,
+		dictPath + `:6: "café" isn't a lowercase ASCII word`,
+		targetPath + `:2: "hello" is 5 letters, expected 7`,
+		targetPath + `:3: "carrots" isn't in the dictionary`,
+		targetPath + `:4: "detract" is a duplicate of line 1`,
+		targetPath + `:5: "Cottage" isn't a lowercase ASCII word`,
+	}
+	if diff := cmp.Diff(want, strings.Split(strings.TrimSpace(out), "\n")); diff != "" {
+		t.Errorf("unexpected problems (-want +got)\n%s", diff)
+	}
+
+	if _, err := run(true); err != nil {
+		t.Fatalf("validate with --fix: %v", err)
+	}
+	for path, want := range map[string]string{
+		dictPath:   "cottage\ndetract\nhello\n",
+		targetPath: "detract\ncottage\n",
+	} {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", path, err)
+		}
+		if diff := cmp.Diff(want, string(got)); diff != "" {
+			t.Errorf("unexpected fixed %s (-want +got)\n%s", filepath.Base(path), diff)
+		}
+	}
+
+	if out, err := run(false); err != nil || out != "" {
+		t.Errorf("fixed word lists still had problems, err = %v:\n%s", err, out)
+	}
+}
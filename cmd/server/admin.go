@@ -0,0 +1,368 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/logging"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+const (
+	adminGamesPath  = "/api/admin/games"
+	adminReloadPath = "/api/admin/reload"
+
+	// maxAdminListDays caps how many days can be listed in one request.
+	maxAdminListDays = 366
+	// defaultAdminListDays is how many days are listed if no end is given.
+	defaultAdminListDays = 30
+)
+
+// adminAuth holds the credentials for the admin API, which accepts either a
+// bearer token or HTTP basic auth. The admin API is disabled if neither is
+// configured.
+type adminAuth struct {
+	token    string
+	username string
+	password string
+}
+
+func (a *adminAuth) enabled() bool {
+	return a.token != "" || (a.username != "" && a.password != "")
+}
+
+func (a *adminAuth) authorized(r *http.Request) bool {
+	if a.token != "" {
+		if tok, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			return secureCompare(tok, a.token)
+		}
+	}
+	if a.username != "" && a.password != "" {
+		if user, pass, ok := r.BasicAuth(); ok {
+			// Evaluate both, so timing doesn't reveal which one was wrong.
+			userOK := secureCompare(user, a.username)
+			passOK := secureCompare(pass, a.password)
+			return userOK && passOK
+		}
+	}
+	return false
+}
+
+func secureCompare(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// wrap rejects requests that aren't authorized.
+func (a *adminAuth) wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.authorized(r) {
+			if a.username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="srordle admin"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="srordle admin"`)
+			}
+			adminError(w, r, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// adminError logs the error and responds with it as JSON, since admin API
+// errors are meant to be read by the caller.
+func adminError(w http.ResponseWriter, r *http.Request, code int, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	logging.FromContext(r.Context()).Info("admin API error", "status", code, "error", msg)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	jsonResp(w, struct {
+		Error string
+	}{msg})
+}
+
+type datedGame struct {
+	Date string
+	Game *srordle.Game
+}
+
+// serveAdminGames handles the following, each of which takes an optional
+// channel query parameter and otherwise uses the default channel:
+//
+//	GET    /api/admin/games?from=YYYY-MM-DD&to=YYYY-MM-DD - List games
+//	GET    /api/admin/games/YYYY-MM-DD - Preview a game, including its target word
+//	POST   /api/admin/games/YYYY-MM-DD - Create a game, if the date doesn't have one
+//	PUT    /api/admin/games/YYYY-MM-DD - Create or replace a game
+//	PATCH  /api/admin/games/YYYY-MM-DD - Update some fields of a game, e.g. swap its target word
+//	DELETE /api/admin/games/YYYY-MM-DD - Delete a game
+func (s *server) serveAdminGames(w http.ResponseWriter, r *http.Request) {
+	ch, err := s.lookupChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		adminError(w, r, http.StatusNotFound, "%v", err)
+		return
+	}
+
+	if r.URL.Path == adminGamesPath || r.URL.Path == adminGamesPath+"/" {
+		if r.Method != http.MethodGet {
+			adminError(w, r, http.StatusMethodNotAllowed, "invalid method %q", r.Method)
+			return
+		}
+		s.serveAdminListGames(w, r, ch)
+		return
+	}
+
+	date, err := db.ParseDate(strings.TrimPrefix(r.URL.Path, adminGamesPath+"/"))
+	if err != nil {
+		adminError(w, r, http.StatusNotFound, "%v", err)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.serveAdminGetGame(w, r, ch, date)
+	case http.MethodPost, http.MethodPut:
+		s.serveAdminSetGame(w, r, ch, date)
+	case http.MethodPatch:
+		s.serveAdminUpdateGame(w, r, ch, date)
+	case http.MethodDelete:
+		s.serveAdminDeleteGame(w, r, ch, date)
+	default:
+		adminError(w, r, http.StatusMethodNotAllowed, "invalid method %q", r.Method)
+	}
+}
+
+func (s *server) serveAdminListGames(w http.ResponseWriter, r *http.Request, ch *channel) {
+	from, to, err := parseDateRange(r, time.Now())
+	if err != nil {
+		adminError(w, r, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	dgs, err := s.db.Games(ch.name, from, to)
+	if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to load games: %v", err)
+		return
+	}
+	games := []datedGame{}
+	for _, dg := range dgs {
+		games = append(games, datedGame{Date: dg.Date.String(), Game: dg.Game})
+	}
+
+	jsonResp(w, struct {
+		Channel string
+		Games   []datedGame
+	}{ch.name, games})
+}
+
+// parseDateRange reads the inclusive range of dates given by the from and to
+// query parameters, defaulting to the next month starting today.
+func parseDateRange(r *http.Request, now time.Time) (db.Date, db.Date, error) {
+	q := r.URL.Query()
+
+	from := db.ToDate(now.UTC())
+	if v := q.Get("from"); v != "" {
+		d, err := db.ParseDate(v)
+		if err != nil {
+			return db.Date{}, db.Date{}, fmt.Errorf("invalid from: %w", err)
+		}
+		from = d
+	}
+
+	to := from.AddDays(defaultAdminListDays - 1)
+	if v := q.Get("to"); v != "" {
+		d, err := db.ParseDate(v)
+		if err != nil {
+			return db.Date{}, db.Date{}, fmt.Errorf("invalid to: %w", err)
+		}
+		to = d
+	}
+
+	days := daysBetween(from, to) + 1
+	if days <= 0 {
+		return db.Date{}, db.Date{}, fmt.Errorf("from (%s) must not be after to (%s)", from, to)
+	}
+	if days > maxAdminListDays {
+		return db.Date{}, db.Date{}, fmt.Errorf("can list at most %d days at once, requested %d", maxAdminListDays, days)
+	}
+	return from, to, nil
+}
+
+func daysBetween(from, to db.Date) int {
+	f := time.Date(int(from.Year), from.Month, int(from.Day), 0, 0, 0, 0, time.UTC)
+	t := time.Date(int(to.Year), to.Month, int(to.Day), 0, 0, 0, 0, time.UTC)
+	return int(t.Sub(f).Hours() / 24)
+}
+
+func (s *server) serveAdminGetGame(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	g, err := s.db.Game(ch.name, date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		adminError(w, r, http.StatusNotFound, "no game for %s", date)
+		return
+	} else if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to load game: %v", err)
+		return
+	}
+
+	jsonResp(w, datedGame{Date: date.String(), Game: g})
+}
+
+// adminGameRequest is the body for creating or updating a game. Fields that
+// aren't set keep their existing values for updates, or get the channel's
+// defaults for new games.
+type adminGameRequest struct {
+	TargetWord   *string
+	Shape        srordle.Shape
+	FullAttempts *int
+}
+
+func (req *adminGameRequest) applyTo(g *srordle.Game) {
+	if req.TargetWord != nil {
+		g.TargetWord = strings.ToLower(*req.TargetWord)
+	}
+	if req.Shape != nil {
+		g.Shape = req.Shape
+	}
+	if req.FullAttempts != nil {
+		g.FullAttempts = *req.FullAttempts
+	}
+}
+
+func (s *server) serveAdminSetGame(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	var req adminGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		adminError(w, r, http.StatusBadRequest, "failed to parse request: %v", err)
+		return
+	}
+	if req.TargetWord == nil {
+		adminError(w, r, http.StatusBadRequest, "TargetWord is required")
+		return
+	}
+
+	g := ch.newGame()
+	req.applyTo(g)
+	if !s.validateAdminGame(w, r, ch, g) {
+		return
+	}
+
+	var err error
+	if r.Method == http.MethodPost {
+		err = s.db.CreateGame(ch.name, date, g)
+	} else {
+		err = s.db.AddGame(ch.name, date, g)
+	}
+	s.games.Invalidate(ch.name, date)
+	if errors.Is(err, db.ErrGameExists) {
+		adminError(w, r, http.StatusConflict, "a game already exists for %s", date)
+		return
+	} else if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to save game: %v", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("admin set game", "channel", ch.name, "date", date, "game", g)
+	if r.Method == http.MethodPost {
+		w.WriteHeader(http.StatusCreated)
+	}
+	jsonResp(w, datedGame{Date: date.String(), Game: g})
+}
+
+func (s *server) serveAdminUpdateGame(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	var req adminGameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		adminError(w, r, http.StatusBadRequest, "failed to parse request: %v", err)
+		return
+	}
+
+	g, err := s.db.Game(ch.name, date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		adminError(w, r, http.StatusNotFound, "no game for %s", date)
+		return
+	} else if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to load game: %v", err)
+		return
+	}
+
+	req.applyTo(g)
+	if !s.validateAdminGame(w, r, ch, g) {
+		return
+	}
+
+	err = s.db.AddGame(ch.name, date, g)
+	s.games.Invalidate(ch.name, date)
+	if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to save game: %v", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("admin updated game", "channel", ch.name, "date", date, "game", g)
+	jsonResp(w, datedGame{Date: date.String(), Game: g})
+}
+
+func (s *server) serveAdminDeleteGame(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	err := s.db.DeleteGame(ch.name, date)
+	s.games.Invalidate(ch.name, date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		adminError(w, r, http.StatusNotFound, "no game for %s", date)
+		return
+	} else if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to delete game: %v", err)
+		return
+	}
+
+	logging.FromContext(r.Context()).Info("admin deleted game", "channel", ch.name, "date", date)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// serveAdminReload handles POST /api/admin/reload, which reloads the
+// dictionary, target words, and definitions from disk, the same as sending the
+// server a SIGHUP.
+func (s *server) serveAdminReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		adminError(w, r, http.StatusMethodNotAllowed, "invalid method %q", r.Method)
+		return
+	}
+
+	results := s.reloadWords()
+	logReload(logging.FromContext(r.Context()), "admin reloaded word lists", results)
+
+	code := http.StatusOK
+	for _, res := range results {
+		if res.Error != "" {
+			code = http.StatusInternalServerError
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	jsonResp(w, struct {
+		Channels map[string]channelReload
+	}{results})
+}
+
+// validateAdminGame checks that the game is playable and its target word is
+// in the channel's dictionary. If it returns false, an error has already been
+// written to the client.
+func (s *server) validateAdminGame(w http.ResponseWriter, r *http.Request, ch *channel, g *srordle.Game) bool {
+	if code, err := s.checkGame(ch, g); err != nil {
+		adminError(w, r, code, "%v", err)
+		return false
+	}
+	return true
+}
+
+// checkGame returns an error and the corresponding HTTP status code if the
+// game isn't playable or its target word isn't in the channel's dictionary.
+func (s *server) checkGame(ch *channel, g *srordle.Game) (int, error) {
+	err := g.CheckPlayable(ch.dict)
+	switch {
+	case errors.Is(err, srordle.ErrInvalidGame):
+		return http.StatusBadRequest, err
+	case err != nil:
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+)
+
+func TestAdminAuth(t *testing.T) {
+	auth := &adminAuth{token: "secret-token", username: "admin", password: "hunter2"}
+	h := auth.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	tests := []struct {
+		desc     string
+		setAuth  func(r *http.Request)
+		wantCode int
+	}{
+		{"no auth", func(r *http.Request) {}, http.StatusUnauthorized},
+		{"good token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer secret-token") }, http.StatusOK},
+		{"bad token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer nope") }, http.StatusUnauthorized},
+		{"good basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "hunter2") }, http.StatusOK},
+		{"bad basic auth", func(r *http.Request) { r.SetBasicAuth("admin", "hunter3") }, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, adminGamesPath, nil)
+			test.setAuth(r)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != test.wantCode {
+				t.Errorf("got status %d, want %d", w.Code, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestAdminGames(t *testing.T) {
+	s := newAdminTestServer(t)
+
+	// Creating a game.
+	w := adminRequest(s, http.MethodPost, "/2022-08-20", `{"TargetWord": "DETRACT"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create returned %d, want %d: %s", w.Code, http.StatusCreated, w.Body)
+	}
+	// Creating it again fails.
+	if w := adminRequest(s, http.MethodPost, "/2022-08-20", `{"TargetWord": "cottage"}`); w.Code != http.StatusConflict {
+		t.Errorf("duplicate create returned %d, want %d", w.Code, http.StatusConflict)
+	}
+	// Words need to be in the dictionary, and the right length.
+	if w := adminRequest(s, http.MethodPut, "/2022-08-21", `{"TargetWord": "abcdefg"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("create with non-dictionary word returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := adminRequest(s, http.MethodPut, "/2022-08-21", `{"TargetWord": "cat"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("create with short word returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	// Previewing it shows the target word.
+	got := decodeDatedGame(t, adminRequest(s, http.MethodGet, "/2022-08-20", ""))
+	if got.Game.TargetWord != "detract" || got.Game.FullAttempts != srordle.DefaultFullAttempts {
+		t.Errorf("unexpected game %+v", got.Game)
+	}
+
+	// Swapping the target word keeps the rest.
+	got = decodeDatedGame(t, adminRequest(s, http.MethodPatch, "/2022-08-20", `{"TargetWord": "cottage"}`))
+	if got.Game.TargetWord != "cottage" || got.Game.FullAttempts != srordle.DefaultFullAttempts {
+		t.Errorf("unexpected game after update %+v", got.Game)
+	}
+
+	if w := adminRequest(s, http.MethodPut, "/2022-08-22", `{"TargetWord": "detract", "FullAttempts": 3}`); w.Code != http.StatusOK {
+		t.Fatalf("put returned %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+
+	// Listing.
+	w = adminRequest(s, http.MethodGet, "?from=2022-08-19&to=2022-08-31", "")
+	var list struct {
+		Games []datedGame
+	}
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil {
+		t.Fatalf("failed to decode list response: %v", err)
+	}
+	if len(list.Games) != 2 || list.Games[0].Date != "2022-08-20" || list.Games[1].Date != "2022-08-22" {
+		t.Errorf("unexpected games listed: %+v", list.Games)
+	}
+
+	// Deleting.
+	if w := adminRequest(s, http.MethodDelete, "/2022-08-20", ""); w.Code != http.StatusNoContent {
+		t.Errorf("delete returned %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if w := adminRequest(s, http.MethodGet, "/2022-08-20", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get after delete returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := adminRequest(s, http.MethodDelete, "/2022-08-20", ""); w.Code != http.StatusNotFound {
+		t.Errorf("second delete returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdminGamesChannels(t *testing.T) {
+	s := newAdminTestServer(t)
+	work := testChannel("work", s.channels[db.DefaultChannel].dict.(*trie.Trie))
+	work.shape = srordle.Shape{srordle.DefaultShape()[0]}
+	work.fullAttempts = 1
+	s.channels["work"] = work
+
+	if w := adminRequest(s, http.MethodPut, "/2022-08-20", `{"TargetWord": "detract"}`); w.Code != http.StatusOK {
+		t.Fatalf("put returned %d: %s", w.Code, w.Body)
+	}
+	if w := adminRequest(s, http.MethodPut, "/2022-08-20?channel=work", `{"TargetWord": "cottage"}`); w.Code != http.StatusOK {
+		t.Fatalf("put in work channel returned %d: %s", w.Code, w.Body)
+	}
+
+	// Each channel has its own game, and new games get the channel's settings.
+	if got := decodeDatedGame(t, adminRequest(s, http.MethodGet, "/2022-08-20", "")); got.Game.TargetWord != "detract" {
+		t.Errorf("unexpected default channel game %+v", got.Game)
+	}
+	got := decodeDatedGame(t, adminRequest(s, http.MethodGet, "/2022-08-20?channel=work", ""))
+	if got.Game.TargetWord != "cottage" || got.Game.FullAttempts != 1 || len(got.Game.Shape) != 1 {
+		t.Errorf("unexpected work channel game %+v", got.Game)
+	}
+
+	if w := adminRequest(s, http.MethodGet, "/2022-08-20?channel=missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("get in unknown channel returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func newAdminTestServer(t *testing.T) *server {
+	dict, err := trie.New(strings.NewReader("detract\ncottage\ncat\n"))
+	if err != nil {
+		t.Fatalf("trie.New: %v", err)
+	}
+	d := db.NewMemory()
+	return &server{
+		channels: map[string]*channel{db.DefaultChannel: testChannel(db.DefaultChannel, dict)},
+		db:       d,
+		games:    newGameCache(d),
+	}
+}
+
+// testChannel returns a channel with the given dictionary, and the default
+// settings for new games.
+func testChannel(name string, dict *trie.Trie) *channel {
+	return &channel{
+		name:         name,
+		words:        staticWordStore(&wordLists{dict: dict}),
+		dict:         dict,
+		shape:        srordle.DefaultShape(),
+		fullAttempts: srordle.DefaultFullAttempts,
+	}
+}
+
+func adminRequest(s *server, method, path, body string) *httptest.ResponseRecorder {
+	var rdr io.Reader
+	if body != "" {
+		rdr = strings.NewReader(body)
+	}
+	r := httptest.NewRequest(method, adminGamesPath+path, rdr)
+	w := httptest.NewRecorder()
+	s.serveAdminGames(w, r)
+	return w
+}
+
+func decodeDatedGame(t *testing.T, w *httptest.ResponseRecorder) datedGame {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("got status %d, want %d: %s", w.Code, http.StatusOK, w.Body)
+	}
+	var dg datedGame
+	if err := json.NewDecoder(w.Body).Decode(&dg); err != nil {
+		t.Fatalf("failed to decode game: %v", err)
+	}
+	if dg.Game == nil {
+		dg.Game = &srordle.Game{}
+	}
+	return dg
+}
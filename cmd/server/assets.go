@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// assetPath maps a request path to a path in the asset filesystem, which
+// contains the compiled frontend under dist/ and images under images/.
+func assetPath(urlPath string) (string, bool) {
+	p := path.Clean("/" + urlPath)
+	if p == "/" {
+		return "dist/index.html", true
+	}
+
+	// Don't serve dotfiles, like dist/.gitignore.
+	for _, part := range strings.Split(p, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+
+	if strings.HasPrefix(p, "/images/") {
+		return strings.TrimPrefix(p, "/"), true
+	}
+	return "dist" + p, true
+}
+
+// assetServer serves the frontend and images, and can return the raw contents
+// of an asset, e.g. to modify index.html before serving it.
+type assetServer interface {
+	http.Handler
+	// ReadAsset returns the contents of the asset at the given asset path,
+	// e.g. dist/index.html.
+	ReadAsset(p string) ([]byte, error)
+}
+
+// diskAssets serves assets from a directory on disk, which is useful in
+// development so that the server doesn't need to be rebuilt when the frontend
+// changes.
+type diskAssets struct {
+	dir string
+}
+
+func (d *diskAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p, ok := assetPath(r.URL.Path)
+	if !ok {
+		httpError(w, r, http.StatusNotFound, "path not allowed", "path", r.URL.Path)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeFile(w, r, filepath.Join(d.dir, filepath.FromSlash(p)))
+}
+
+func (d *diskAssets) ReadAsset(p string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(d.dir, filepath.FromSlash(p)))
+}
+
+type asset struct {
+	contentType  string
+	cacheControl string
+	etag         string
+
+	raw []byte
+	// gzip and br are nil if compression wouldn't help, e.g. for PNGs.
+	gzip []byte
+	br   []byte
+}
+
+// embeddedAssets serves assets from memory, with everything compressed ahead
+// of time.
+type embeddedAssets struct {
+	// files is keyed by asset path, e.g. dist/index.html.
+	files map[string]*asset
+}
+
+func loadEmbeddedAssets(fsys fs.FS) (*embeddedAssets, error) {
+	ea := &embeddedAssets{files: make(map[string]*asset)}
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		dat, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %w", p, err)
+		}
+
+		a, err := newAsset(p, dat)
+		if err != nil {
+			return fmt.Errorf("failed to load %q: %w", p, err)
+		}
+		ea.files[p] = a
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk assets: %w", err)
+	}
+	return ea, nil
+}
+
+func newAsset(p string, dat []byte) (*asset, error) {
+	sum := sha256.Sum256(dat)
+	a := &asset{
+		contentType: mime.TypeByExtension(path.Ext(p)),
+		etag:        hex.EncodeToString(sum[:8]),
+		raw:         dat,
+	}
+	if a.contentType == "" {
+		a.contentType = http.DetectContentType(dat)
+	}
+
+	// The compiled frontend doesn't have content hashes in its file names, so
+	// clients need to check back each time. Images basically never change.
+	if strings.HasPrefix(p, "images/") {
+		a.cacheControl = "public, max-age=86400"
+	} else {
+		a.cacheControl = "no-cache"
+	}
+
+	if !compressible(a.contentType) {
+		return a, nil
+	}
+
+	var gzBuf bytes.Buffer
+	gw, err := gzip.NewWriterLevel(&gzBuf, gzip.BestCompression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init gzip writer: %w", err)
+	}
+	if _, err := gw.Write(dat); err != nil {
+		return nil, fmt.Errorf("failed to gzip: %w", err)
+	}
+	if err := gw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close gzip writer: %w", err)
+	}
+	if gzBuf.Len() < len(dat) {
+		a.gzip = gzBuf.Bytes()
+	}
+
+	var brBuf bytes.Buffer
+	bw := brotli.NewWriterLevel(&brBuf, brotli.BestCompression)
+	if _, err := bw.Write(dat); err != nil {
+		return nil, fmt.Errorf("failed to brotli compress: %w", err)
+	}
+	if err := bw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close brotli writer: %w", err)
+	}
+	if brBuf.Len() < len(dat) {
+		a.br = brBuf.Bytes()
+	}
+
+	return a, nil
+}
+
+func compressible(contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mt, "text/") {
+		return true
+	}
+	switch mt {
+	case "application/javascript", "application/json", "application/xml", "image/svg+xml", "image/x-icon", "image/vnd.microsoft.icon":
+		return true
+	}
+	return false
+}
+
+func (ea *embeddedAssets) ReadAsset(p string) ([]byte, error) {
+	a, ok := ea.files[p]
+	if !ok {
+		return nil, fmt.Errorf("asset %q: %w", p, fs.ErrNotExist)
+	}
+	return a.raw, nil
+}
+
+func (ea *embeddedAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+
+	p, ok := assetPath(r.URL.Path)
+	if !ok {
+		httpError(w, r, http.StatusNotFound, "path not allowed", "path", r.URL.Path)
+		return
+	}
+	a, ok := ea.files[p]
+	if !ok {
+		httpError(w, r, http.StatusNotFound, "path not found", "path", r.URL.Path)
+		return
+	}
+
+	body, encoding, etag := a.raw, "", a.etag
+	accept := r.Header.Get("Accept-Encoding")
+	switch {
+	case a.br != nil && acceptsEncoding(accept, "br"):
+		body, encoding, etag = a.br, "br", a.etag+"-br"
+	case a.gzip != nil && acceptsEncoding(accept, "gzip"):
+		body, encoding, etag = a.gzip, "gzip", a.etag+"-gz"
+	}
+	etag = `"` + etag + `"`
+
+	h := w.Header()
+	h.Set("Content-Type", a.contentType)
+	h.Set("Cache-Control", a.cacheControl)
+	h.Set("ETag", etag)
+	if a.gzip != nil || a.br != nil {
+		h.Set("Vary", "Accept-Encoding")
+	}
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if encoding != "" {
+		h.Set("Content-Encoding", encoding)
+	}
+	h.Set("Content-Length", fmt.Sprint(len(body)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(body)
+}
+
+// acceptsEncoding returns true if the given Accept-Encoding header allows the
+// given content coding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+		// A q-value of zero explicitly disallows the coding.
+		params = strings.TrimSpace(params)
+		if !strings.HasPrefix(params, "q=") {
+			return true
+		}
+		q, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64)
+		return err == nil && q > 0
+	}
+	return false
+}
+
+// etagMatches returns true if the given If-None-Match header matches the
+// given ETag, using the weak comparison function, see RFC 9110 section 13.1.2.
+func etagMatches(header, etag string) bool {
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "*" || strings.TrimPrefix(part, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestEmbeddedAssets(t *testing.T) {
+	html := strings.Repeat("<p>Srordle</p>", 100)
+	ea, err := loadEmbeddedAssets(fstest.MapFS{
+		"dist/.gitignore":    {Data: []byte("*")},
+		"dist/index.html":    {Data: []byte(html)},
+		"images/favicon.png": {Data: []byte("\x89PNG not really")},
+	})
+	if err != nil {
+		t.Fatalf("loadEmbeddedAssets: %v", err)
+	}
+
+	// Plain request.
+	w := serveAsset(ea, "/", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET / returned %d, want %d", w.Code, http.StatusOK)
+	}
+	if got := w.Body.String(); got != html {
+		t.Errorf("GET / returned %q, want %q", got, html)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("Cache-Control = %q, want %q", got, "no-cache")
+	}
+
+	// Compressed request.
+	w = serveAsset(ea, "/index.html", map[string]string{"Accept-Encoding": "gzip, br;q=0"})
+	if got := w.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q, want gzip", got)
+	}
+	gr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	dat, err := io.ReadAll(gr)
+	if err != nil {
+		t.Fatalf("failed to read gzipped body: %v", err)
+	}
+	if !bytes.Equal(dat, []byte(html)) {
+		t.Errorf("gzipped body was %q, want %q", dat, html)
+	}
+
+	// Conditional request.
+	etag := w.Header().Get("ETag")
+	w = serveAsset(ea, "/index.html", map[string]string{"Accept-Encoding": "gzip", "If-None-Match": etag})
+	if w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET returned %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	// Images are cached, and not compressed.
+	w = serveAsset(ea, "/images/favicon.png", map[string]string{"Accept-Encoding": "gzip, br"})
+	if got := w.Header().Get("Content-Encoding"); got != "" {
+		t.Errorf("Content-Encoding for PNG = %q, want none", got)
+	}
+	if got := w.Header().Get("Cache-Control"); !strings.Contains(got, "max-age") {
+		t.Errorf("Cache-Control for image = %q, want a max-age", got)
+	}
+
+	for _, p := range []string{"/.gitignore", "/missing.js", "/images/../dist/.gitignore"} {
+		if w := serveAsset(ea, p, nil); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s returned %d, want %d", p, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func serveAsset(h http.Handler, p string, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, p, nil)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// gameCache keeps the games that are currently live somewhere in the world,
+// plus the next day's game, in memory, so serving them doesn't need a database
+// transaction per request.
+//
+// Entries are reloaded once they're older than ttl, since games can be changed
+// by other processes sharing the database, like the CLI, which can't call
+// Invalidate.
+type gameCache struct {
+	db  db.Store
+	now func() time.Time
+	ttl time.Duration
+
+	mu    sync.RWMutex
+	games map[cacheKey]cachedGame
+}
+
+// gameCacheTTL is how long a cached game is used before it's reloaded.
+const gameCacheTTL = 30 * time.Second
+
+type cachedGame struct {
+	game     *srordle.Game
+	loadedAt time.Time
+}
+
+type cacheKey struct {
+	channel string
+	date    db.Date
+}
+
+func newGameCache(d db.Store) *gameCache {
+	return &gameCache{
+		db:    d,
+		now:   time.Now,
+		ttl:   gameCacheTTL,
+		games: make(map[cacheKey]cachedGame),
+	}
+}
+
+// window returns the range of dates that should be cached.
+func (c *gameCache) window() (db.Date, db.Date) {
+	first, last := liveDates(c.now())
+	return first, last.AddDays(1)
+}
+
+func inRange(d, first, last db.Date) bool {
+	return daysBetween(first, d) >= 0 && daysBetween(d, last) >= 0
+}
+
+// Game returns the game for the given channel and date, from memory if
+// possible. The returned game is a copy, and can be modified by the caller.
+func (c *gameCache) Game(channel string, date db.Date) (*srordle.Game, error) {
+	key := cacheKey{channel: channel, date: date}
+	now := c.now()
+	c.mu.RLock()
+	cg, ok := c.games[key]
+	c.mu.RUnlock()
+	if ok && now.Sub(cg.loadedAt) < c.ttl {
+		return cg.game.Clone(), nil
+	}
+
+	g, err := c.db.Game(channel, date)
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := c.window()
+	if !inRange(date, first, last) {
+		return g, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Drop anything that has rolled out of the window.
+	for k := range c.games {
+		if !inRange(k.date, first, last) {
+			delete(c.games, k)
+		}
+	}
+	c.games[key] = cachedGame{game: g, loadedAt: now}
+	return g.Clone(), nil
+}
+
+// Invalidate removes the given channel and date from the cache, and should be
+// called whenever its game is changed.
+func (c *gameCache) Invalidate(channel string, date db.Date) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.games, cacheKey{channel: channel, date: date})
+}
+
+// nextRollover returns the next UTC midnight after the given time, which is
+// when the set of cached games changes.
+func nextRollover(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+func TestGameCache(t *testing.T) {
+	d := db.NewMemory()
+	c := newGameCache(d)
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	today := db.ToDate(now)
+	old := today.AddDays(-30)
+	later := today.AddDays(3)
+	for _, date := range []db.Date{today, old, later} {
+		if err := d.AddGame(db.DefaultChannel, date, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+
+	for _, date := range []db.Date{today, old} {
+		g, err := c.Game(db.DefaultChannel, date)
+		if err != nil {
+			t.Fatalf("Game(%s): %v", date, err)
+		}
+		// Modifying the returned game shouldn't affect the cache.
+		g.TargetWord = ""
+	}
+	if _, ok := c.games[cacheKey{db.DefaultChannel, today}]; !ok {
+		t.Error("today's game wasn't cached")
+	}
+	if _, ok := c.games[cacheKey{db.DefaultChannel, old}]; ok {
+		t.Error("a game from a month ago was cached")
+	}
+
+	assertWord := func(want string) {
+		t.Helper()
+		g, err := c.Game(db.DefaultChannel, today)
+		if err != nil {
+			t.Fatalf("Game(%s): %v", today, err)
+		}
+		if g.TargetWord != want {
+			t.Errorf("target word = %q, want %q", g.TargetWord, want)
+		}
+	}
+	assertWord("detract")
+
+	// Changes don't show up until the cache is invalidated.
+	if err := d.AddGame(db.DefaultChannel, today, &srordle.Game{TargetWord: "cottage", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	assertWord("detract")
+	c.Invalidate(db.DefaultChannel, today)
+	assertWord("cottage")
+
+	// Or until the entry expires, for changes made behind the cache's back, e.g.
+	// by the CLI sharing the database.
+	if err := d.AddGame(db.DefaultChannel, today, &srordle.Game{TargetWord: "carrots", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	now = now.Add(c.ttl - time.Second)
+	assertWord("cottage")
+	now = now.Add(time.Second)
+	assertWord("carrots")
+
+	// Once a day is over everywhere, it gets dropped.
+	now = now.Add(72 * time.Hour)
+	if _, err := c.Game(db.DefaultChannel, later); err != nil {
+		t.Fatalf("Game(%s): %v", later, err)
+	}
+	if _, ok := c.games[cacheKey{db.DefaultChannel, today}]; ok {
+		t.Error("game from three days ago is still cached")
+	}
+}
+
+func TestNextRollover(t *testing.T) {
+	tz := time.FixedZone("UTC-5", -5*60*60)
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC), time.Date(2022, time.August, 21, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2022, time.August, 20, 0, 0, 0, 0, time.UTC), time.Date(2022, time.August, 21, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2022, time.December, 31, 23, 59, 0, 0, time.UTC), time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		// 8pm on the 20th in UTC-5 is already the 21st in UTC.
+		{time.Date(2022, time.August, 20, 20, 0, 0, 0, tz), time.Date(2022, time.August, 22, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, test := range tests {
+		if got := nextRollover(test.now); !got.Equal(test.want) {
+			t.Errorf("nextRollover(%s) = %s, want %s", test.now, got, test.want)
+		}
+	}
+}
+
+func TestSrordleByDate(t *testing.T) {
+	s := newAdminTestServer(t)
+	today := db.ToDate(time.Now().UTC())
+	if err := s.db.AddGame(db.DefaultChannel, today, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	get := func(date, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, srordlePath+date, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		s.serveSrordleByDate(w, r)
+		return w
+	}
+
+	w := get(today.String(), "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET returned %d, want %d", w.Code, http.StatusOK)
+	}
+	if strings.Contains(w.Body.String(), "detract") {
+		t.Errorf("response included the target word: %s", w.Body)
+	}
+	if cc := w.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public, max-age=") || !strings.Contains(cc, "s-maxage=") {
+		t.Errorf("Cache-Control = %q, want a public max-age and s-maxage", cc)
+	}
+	if _, err := http.ParseTime(w.Header().Get("Expires")); err != nil {
+		t.Errorf("invalid Expires header: %v", err)
+	}
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag in response")
+	}
+
+	if w := get(today.String(), etag); w.Code != http.StatusNotModified {
+		t.Errorf("conditional GET returned %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	// Changing the shape through the admin API changes the ETag.
+	if w := adminRequest(s, http.MethodPatch, "/"+today.String(), `{"FullAttempts": 3}`); w.Code != http.StatusOK {
+		t.Fatalf("PATCH returned %d: %s", w.Code, w.Body)
+	}
+	if w := get(today.String(), etag); w.Code != http.StatusOK {
+		t.Errorf("conditional GET after update returned %d, want %d", w.Code, http.StatusOK)
+	}
+
+	if w := get(today.AddDays(-1).String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET for date without a game returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := get(today.AddDays(5).String(), ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET for a future date returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if w := get("not-a-date", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET for invalid date returned %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
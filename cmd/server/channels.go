@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+
+	"github.com/bcspragu/srordle/config"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// channel is a separate set of games, with its own schedule, word lists, and
+// settings for new games.
+type channel struct {
+	name  string
+	words *wordStore
+	// dict is words, with lookups instrumented.
+	dict         Dictionary
+	shape        srordle.Shape
+	fullAttempts int
+}
+
+// newGame returns a game with the channel's settings for new games.
+func (c *channel) newGame() *srordle.Game {
+	return &srordle.Game{
+		Shape:        c.shape,
+		FullAttempts: c.fullAttempts,
+	}
+}
+
+// loadChannels loads the word lists for every configured channel.
+func loadChannels(cfg *config.Config) (map[string]*channel, error) {
+	// Channels that use the same word lists share them, so we don't keep a copy
+	// of the same dictionary for each one.
+	stores := make(map[wordPaths]*wordStore)
+
+	out := make(map[string]*channel)
+	for _, name := range cfg.ChannelNames() {
+		chCfg, _ := cfg.Channel(name)
+		paths := wordPaths{
+			dict:        chCfg.DictionaryPath,
+			targetWords: chCfg.TargetWordsPath,
+			definitions: chCfg.DefinitionsPath,
+		}
+		ws, ok := stores[paths]
+		if !ok {
+			var err error
+			if ws, err = newWordStore(paths); err != nil {
+				return nil, fmt.Errorf("failed to load word lists for channel %q: %w", name, err)
+			}
+			stores[paths] = ws
+		}
+
+		shape, err := chCfg.GameShape()
+		if err != nil {
+			return nil, fmt.Errorf("invalid shape for channel %q: %w", name, err)
+		}
+		out[name] = &channel{
+			name:         name,
+			words:        ws,
+			dict:         instrumentedDict{ws},
+			shape:        shape,
+			fullAttempts: chCfg.GameFullAttempts(),
+		}
+	}
+	return out, nil
+}
+
+// lookupChannel returns the channel with the given name, where an empty name
+// means the default channel.
+func (s *server) lookupChannel(name string) (*channel, error) {
+	if name == "" {
+		name = db.DefaultChannel
+	}
+	ch, ok := s.channels[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown channel %q", name)
+	}
+	return ch, nil
+}
+
+// channelNames returns the names of every channel, starting with the default
+// one.
+func (s *server) channelNames() []string {
+	names := []string{db.DefaultChannel}
+	for name := range s.channels {
+		if name != db.DefaultChannel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+type channelReload struct {
+	Result *reloadResult `json:",omitempty"`
+	Error  string        `json:",omitempty"`
+}
+
+// reloadWords reloads the word lists for every channel, returning the results
+// by channel name. Word lists shared between channels are only loaded once.
+func (s *server) reloadWords() map[string]channelReload {
+	byStore := make(map[*wordStore]channelReload)
+	out := make(map[string]channelReload)
+	for name, ch := range s.channels {
+		res, ok := byStore[ch.words]
+		if !ok {
+			r, err := ch.words.Reload()
+			if err != nil {
+				res = channelReload{Error: err.Error()}
+			} else {
+				res = channelReload{Result: r}
+			}
+			byStore[ch.words] = res
+		}
+		out[name] = res
+	}
+	return out
+}
+
+func logReload(l *slog.Logger, msg string, results map[string]channelReload) {
+	for name, res := range results {
+		if res.Error != "" {
+			l.Error("failed to reload word lists, keeping the old ones", "channel", name, "error", res.Error)
+			continue
+		}
+		l.Info(msg, "channel", name, "dictionary", res.Result.Dictionary, "target_words", res.Result.TargetWords, "definitions", res.Result.Definitions)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/logging"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+const (
+	dashboardPath      = "/admin/"
+	dashboardGamesPath = "/admin/games/"
+
+	// repeatWindowDays is how far before and after the displayed month we look
+	// for games with the same target word.
+	repeatWindowDays = 365
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var dashboardTemplates = template.Must(template.New("").ParseFS(templateFS, "templates/*.html"))
+
+type calendarDay struct {
+	Date    db.Date
+	InMonth bool
+	Today   bool
+	Game    *srordle.Game
+	// RepeatedOn lists the other dates near this one that have the same target
+	// word.
+	RepeatedOn []string
+}
+
+func (c calendarDay) Missing() bool {
+	return c.InMonth && c.Game == nil
+}
+
+type calendarPage struct {
+	Title string
+	// Channel is the channel being shown, and Channels lists all of them, for
+	// switching between them.
+	Channel      string
+	Channels     []string
+	Month        string
+	PrevMonth    string
+	NextMonth    string
+	Weekdays     []string
+	Weeks        [][]calendarDay
+	MissingCount int
+	RepeatCount  int
+}
+
+type editPage struct {
+	Title   string
+	Channel string
+	Date    string
+	Month   string
+	Exists  bool
+	Game    *srordle.Game
+	// Rows is the shape being edited, with an extra blank row at the end for
+	// adding to it.
+	Rows  []srordle.Row
+	Error string
+}
+
+// serveDashboard handles the following, each of which takes an optional
+// channel query parameter and otherwise shows the default channel:
+//
+//	GET  /admin/?month=YYYY-MM - A calendar of scheduled games
+//	GET  /admin/games/YYYY-MM-DD - A form for editing a single date
+//	POST /admin/games/YYYY-MM-DD - Save or delete the game for a single date
+func (s *server) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && !sameOrigin(r) {
+		httpError(w, r, http.StatusForbidden, "cross-origin dashboard request", "origin", r.Header.Get("Origin"))
+		return
+	}
+
+	ch, err := s.lookupChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "unknown dashboard channel", "error", err)
+		return
+	}
+
+	if dateStr, ok := strings.CutPrefix(r.URL.Path, dashboardGamesPath); ok {
+		date, err := db.ParseDate(dateStr)
+		if err != nil {
+			httpError(w, r, http.StatusNotFound, "invalid dashboard date", "error", err)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			s.serveDashboardEdit(w, r, ch, date)
+		case http.MethodPost:
+			s.serveDashboardSave(w, r, ch, date)
+		default:
+			httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		}
+		return
+	}
+
+	if r.URL.Path != dashboardPath {
+		httpError(w, r, http.StatusNotFound, "dashboard path not found", "path", r.URL.Path)
+		return
+	}
+	if r.Method != http.MethodGet {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+	s.serveDashboardCalendar(w, r, ch)
+}
+
+// sameOrigin guards form submissions against cross-site request forgery, since
+// browsers will happily resend basic auth credentials on them.
+func sameOrigin(r *http.Request) bool {
+	src := r.Header.Get("Origin")
+	if src == "" {
+		src = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(src)
+	if err != nil || src == "" {
+		return false
+	}
+	return u.Host == r.Host
+}
+
+func (s *server) serveDashboardCalendar(w http.ResponseWriter, r *http.Request, ch *channel) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if v := r.URL.Query().Get("month"); v != "" {
+		m, err := time.Parse("2006-01", v)
+		if err != nil {
+			httpError(w, r, http.StatusBadRequest, "invalid month", "month", v, "error", err)
+			return
+		}
+		month = m
+	}
+
+	page, err := s.calendar(ch, month, db.ToDate(now))
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to build calendar", "error", err)
+		return
+	}
+	renderTemplate(w, r, http.StatusOK, "calendar.html", page)
+}
+
+func (s *server) calendar(ch *channel, month time.Time, today db.Date) (*calendarPage, error) {
+	monthStart := db.ToDate(month)
+	monthEnd := db.ToDate(month.AddDate(0, 1, -1))
+
+	// Load everything in the window around the month, so we can spot repeats.
+	byDate := make(map[db.Date]*srordle.Game)
+	datesByWord := make(map[string][]db.Date)
+	from, to := monthStart.AddDays(-repeatWindowDays), monthEnd.AddDays(repeatWindowDays)
+	err := s.db.EachGame(ch.name, from, to, func(dg db.DatedGame) error {
+		byDate[dg.Date] = dg.Game
+		datesByWord[dg.Game.TargetWord] = append(datesByWord[dg.Game.TargetWord], dg.Date)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load games: %w", err)
+	}
+
+	page := &calendarPage{
+		Title:     month.Format("January 2006"),
+		Channel:   ch.name,
+		Channels:  s.channelNames(),
+		Month:     month.Format("2006-01"),
+		PrevMonth: month.AddDate(0, -1, 0).Format("2006-01"),
+		NextMonth: month.AddDate(0, 1, 0).Format("2006-01"),
+		Weekdays:  []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	}
+
+	// Show whole weeks, from the Sunday on or before the first of the month to
+	// the Saturday on or after the end of it.
+	start := monthStart.AddDays(-int(month.Weekday()))
+	end := monthEnd.AddDays(6 - int(month.AddDate(0, 1, -1).Weekday()))
+	var week []calendarDay
+	for d := start; d != end.AddDays(1); d = d.AddDays(1) {
+		day := calendarDay{
+			Date:    d,
+			InMonth: d.Month == monthStart.Month,
+			Today:   d == today,
+			Game:    byDate[d],
+		}
+		if day.Game != nil && day.InMonth {
+			for _, other := range datesByWord[day.Game.TargetWord] {
+				if other != d {
+					day.RepeatedOn = append(day.RepeatedOn, other.String())
+				}
+			}
+			if len(day.RepeatedOn) > 0 {
+				page.RepeatCount++
+			}
+		}
+		if day.Missing() {
+			page.MissingCount++
+		}
+
+		week = append(week, day)
+		if len(week) == 7 {
+			page.Weeks = append(page.Weeks, week)
+			week = nil
+		}
+	}
+	return page, nil
+}
+
+func (s *server) serveDashboardEdit(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	g, err := s.db.Game(ch.name, date)
+	exists := true
+	if errors.Is(err, db.ErrGameNotFound) {
+		exists = false
+		g = ch.newGame()
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", date, "error", err)
+		return
+	}
+	renderTemplate(w, r, http.StatusOK, "edit.html", newEditPage(ch, date, g, exists, ""))
+}
+
+func newEditPage(ch *channel, date db.Date, g *srordle.Game, exists bool, errMsg string) *editPage {
+	rows := append([]srordle.Row{}, g.Shape...)
+	rows = append(rows, make(srordle.Row, srordle.WordLength))
+	return &editPage{
+		Title:   "Edit " + date.String(),
+		Channel: ch.name,
+		Date:    date.String(),
+		Month:   fmt.Sprintf("%04d-%02d", date.Year, int(date.Month)),
+		Exists:  exists,
+		Game:    g,
+		Rows:    rows,
+		Error:   errMsg,
+	}
+}
+
+func (s *server) serveDashboardSave(w http.ResponseWriter, r *http.Request, ch *channel, date db.Date) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to parse form", "error", err)
+		return
+	}
+	month := fmt.Sprintf("%04d-%02d", date.Year, int(date.Month))
+	redirect := dashboardPath + "?" + url.Values{"channel": {ch.name}, "month": {month}}.Encode()
+
+	if r.PostForm.Get("action") == "delete" {
+		err := s.db.DeleteGame(ch.name, date)
+		s.games.Invalidate(ch.name, date)
+		if err != nil && !errors.Is(err, db.ErrGameNotFound) {
+			httpError(w, r, http.StatusInternalServerError, "failed to delete game", "channel", ch.name, "date", date, "error", err)
+			return
+		}
+		logging.FromContext(r.Context()).Info("admin deleted game", "channel", ch.name, "date", date)
+		http.Redirect(w, r, redirect, http.StatusSeeOther)
+		return
+	}
+
+	g, err := gameFromForm(r.PostForm)
+	if err == nil {
+		_, err = s.checkGame(ch, g)
+	}
+	if err != nil {
+		_, getErr := s.db.Game(ch.name, date)
+		renderTemplate(w, r, http.StatusBadRequest, "edit.html", newEditPage(ch, date, g, getErr == nil, err.Error()))
+		return
+	}
+
+	err = s.db.AddGame(ch.name, date, g)
+	s.games.Invalidate(ch.name, date)
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to save game", "channel", ch.name, "date", date, "error", err)
+		return
+	}
+	logging.FromContext(r.Context()).Info("admin set game", "channel", ch.name, "date", date, "game", g)
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+// gameFromForm reads a game from the edit form. The shape is submitted as
+// checkboxes named cell-<row>-<col>, and rows with nothing checked are dropped.
+// Even on error, it returns the game as submitted, so it can be shown again.
+func gameFromForm(form url.Values) (*srordle.Game, error) {
+	g := &srordle.Game{
+		TargetWord: strings.ToLower(strings.TrimSpace(form.Get("target_word"))),
+	}
+
+	fa, err := strconv.Atoi(form.Get("full_attempts"))
+	if err != nil {
+		return g, fmt.Errorf("full attempts must be a number: %w", err)
+	}
+	g.FullAttempts = fa
+
+	rows := make(map[int]srordle.Row)
+	for k := range form {
+		rest, ok := strings.CutPrefix(k, "cell-")
+		if !ok {
+			continue
+		}
+		rowStr, colStr, ok := strings.Cut(rest, "-")
+		if !ok {
+			return g, fmt.Errorf("invalid shape cell %q", k)
+		}
+		row, rowErr := strconv.Atoi(rowStr)
+		col, colErr := strconv.Atoi(colStr)
+		if rowErr != nil || colErr != nil || row < 0 || col < 0 || col >= srordle.WordLength {
+			return g, fmt.Errorf("invalid shape cell %q", k)
+		}
+		if rows[row] == nil {
+			rows[row] = make(srordle.Row, srordle.WordLength)
+		}
+		rows[row][col] = true
+	}
+
+	idxs := make([]int, 0, len(rows))
+	for idx := range rows {
+		idxs = append(idxs, idx)
+	}
+	sort.Ints(idxs)
+	for _, idx := range idxs {
+		g.Shape = append(g.Shape, rows[idx])
+	}
+	return g, nil
+}
+
+func renderTemplate(w http.ResponseWriter, r *http.Request, code int, name string, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := dashboardTemplates.ExecuteTemplate(w, name, data); err != nil {
+		logging.FromContext(r.Context()).Error("failed to render template", "template", name, "error", err)
+	}
+}
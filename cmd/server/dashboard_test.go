@@ -0,0 +1,133 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+func TestDashboardCalendar(t *testing.T) {
+	s := newAdminTestServer(t)
+	add := func(date, word string) {
+		d, err := db.ParseDate(date)
+		if err != nil {
+			t.Fatalf("ParseDate: %v", err)
+		}
+		if err := s.db.AddGame(db.DefaultChannel, d, &srordle.Game{TargetWord: word, Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+	add("2022-08-01", "detract")
+	add("2022-08-02", "cottage")
+	add("2022-09-15", "detract")
+
+	month := time.Date(2022, time.August, 1, 0, 0, 0, 0, time.UTC)
+	page, err := s.calendar(s.channels[db.DefaultChannel], month, db.Date{Year: 2022, Month: time.August, Day: 2})
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+
+	// August 2022 starts on a Monday and ends on a Wednesday.
+	if n := len(page.Weeks); n != 5 {
+		t.Errorf("calendar had %d weeks, want 5", n)
+	}
+	if first := page.Weeks[0][0].Date; first != (db.Date{Year: 2022, Month: time.July, Day: 31}) {
+		t.Errorf("calendar started on %v, want 2022-07-31", first)
+	}
+	if page.MissingCount != 29 {
+		t.Errorf("MissingCount = %d, want 29", page.MissingCount)
+	}
+	if page.RepeatCount != 1 {
+		t.Errorf("RepeatCount = %d, want 1", page.RepeatCount)
+	}
+	if got := page.Weeks[0][1].RepeatedOn; len(got) != 1 || got[0] != "2022-09-15" {
+		t.Errorf("2022-08-01 RepeatedOn = %v, want [2022-09-15]", got)
+	}
+
+	w := httptest.NewRecorder()
+	s.serveDashboard(w, httptest.NewRequest(http.MethodGet, "/admin/?month=2022-08", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("calendar returned %d, want %d", w.Code, http.StatusOK)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "Also used on 2022-09-15") {
+		t.Errorf("calendar didn't flag the repeated word:\n%s", body)
+	}
+}
+
+func TestDashboardSave(t *testing.T) {
+	s := newAdminTestServer(t)
+
+	form := url.Values{
+		"target_word":   {"Detract"},
+		"full_attempts": {"1"},
+		"cell-0-0":      {"on"},
+		"cell-0-1":      {"on"},
+		"cell-0-2":      {"on"},
+		"cell-0-3":      {"on"},
+		"cell-0-4":      {"on"},
+		"cell-0-5":      {"on"},
+		"cell-0-6":      {"on"},
+		"cell-2-2":      {"on"},
+		"cell-2-3":      {"on"},
+		"cell-2-4":      {"on"},
+	}
+
+	// Cross-origin submissions are rejected.
+	r := newFormRequest("/admin/games/2022-08-20", form)
+	r.Header.Set("Origin", "https://evil.example.com")
+	w := httptest.NewRecorder()
+	s.serveDashboard(w, r)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("cross-origin save returned %d, want %d", w.Code, http.StatusForbidden)
+	}
+
+	w = httptest.NewRecorder()
+	s.serveDashboard(w, newFormRequest("/admin/games/2022-08-20", form))
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("save returned %d, want %d: %s", w.Code, http.StatusSeeOther, w.Body)
+	}
+
+	g, err := s.db.Game(db.DefaultChannel, db.Date{Year: 2022, Month: time.August, Day: 20})
+	if err != nil {
+		t.Fatalf("failed to load saved game: %v", err)
+	}
+	t0, f := true, false
+	want := srordle.Shape{
+		{t0, t0, t0, t0, t0, t0, t0},
+		{f, f, t0, t0, t0, f, f},
+	}
+	if g.TargetWord != "detract" || g.FullAttempts != 1 || len(g.Shape) != 2 {
+		t.Fatalf("unexpected saved game %+v", g)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if g.Shape[i][j] != want[i][j] {
+				t.Errorf("shape[%d][%d] = %t, want %t", i, j, g.Shape[i][j], want[i][j])
+			}
+		}
+	}
+
+	// Invalid games re-render the form with an error.
+	form.Set("target_word", "notaword")
+	w = httptest.NewRecorder()
+	s.serveDashboard(w, newFormRequest("/admin/games/2022-08-20", form))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid save returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), `class="error"`) {
+		t.Errorf("invalid save didn't show an error:\n%s", w.Body)
+	}
+}
+
+func newFormRequest(path string, form url.Values) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", "http://"+r.Host)
+	return r
+}
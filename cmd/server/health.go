@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+)
+
+// liveDates returns the range of dates that are "today" somewhere in the
+// world at the given time, from UTC-12 to UTC+14.
+func liveDates(now time.Time) (first, last db.Date) {
+	first = db.ToDate(now.In(time.FixedZone("UTC-12", -12*60*60)))
+	last = db.ToDate(now.In(time.FixedZone("UTC+14", 14*60*60)))
+	return first, last
+}
+
+// isLive returns true if the date is today somewhere in the world at the given
+// time. Games are only played on live dates, so that clients can't pick a
+// future date and play it early to learn its answer.
+func isLive(d db.Date, now time.Time) bool {
+	first, last := liveDates(now)
+	return inRange(d, first, last)
+}
+
+// serveHealthz reports whether the server is up at all.
+func (s *server) serveHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+type readinessCheck struct {
+	Name  string
+	OK    bool
+	Error string `json:",omitempty"`
+}
+
+// serveReadyz reports whether the server is ready to serve games, meaning the
+// database is open, and every channel's dictionary is loaded and has games
+// scheduled for today (in every timezone) and the next few days.
+func (s *server) serveReadyz(w http.ResponseWriter, r *http.Request) {
+	checks := []readinessCheck{
+		toCheck("serving", s.checkServing()),
+		toCheck("database", s.checkDB()),
+	}
+	now := time.Now()
+	for _, name := range s.channelNames() {
+		ch := s.channels[name]
+		// Keep the default channel's check names the same as before channels.
+		prefix := ""
+		if name != db.DefaultChannel {
+			prefix = name + "/"
+		}
+		checks = append(checks,
+			toCheck(prefix+"dictionary", checkDictionary(ch)),
+			toCheck(prefix+"games", s.checkGames(ch, now)),
+		)
+	}
+
+	ready := true
+	for _, c := range checks {
+		ready = ready && c.OK
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	jsonResp(w, struct {
+		Ready  bool
+		Checks []readinessCheck
+	}{ready, checks})
+}
+
+func toCheck(name string, err error) readinessCheck {
+	if err != nil {
+		return readinessCheck{Name: name, Error: err.Error()}
+	}
+	return readinessCheck{Name: name, OK: true}
+}
+
+func (s *server) checkServing() error {
+	if s.shuttingDown.Load() {
+		return errors.New("server is shutting down")
+	}
+	return nil
+}
+
+func (s *server) checkDB() error {
+	if !s.db.IsOpen() {
+		return errors.New("database is closed")
+	}
+	return nil
+}
+
+func checkDictionary(ch *channel) error {
+	if ch == nil || ch.dict == nil || ch.dict.Size() == 0 {
+		return errors.New("dictionary is empty")
+	}
+	return nil
+}
+
+func (s *server) checkGames(ch *channel, now time.Time) error {
+	if ch == nil {
+		return errors.New("channel isn't configured")
+	}
+	first, last := liveDates(now)
+	last = last.AddDays(s.readyDaysAhead)
+
+	var missing []string
+	for d := first; d != last.AddDays(1); d = d.AddDays(1) {
+		_, err := s.db.Game(ch.name, d)
+		if errors.Is(err, db.ErrGameNotFound) {
+			missing = append(missing, d.String())
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load game for %s: %w", d, err)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no games scheduled for %v", missing)
+	}
+	return nil
+}
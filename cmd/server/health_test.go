@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+)
+
+func TestLiveDates(t *testing.T) {
+	now := time.Date(2022, time.August, 20, 11, 0, 0, 0, time.UTC)
+	first, last := liveDates(now)
+	if want := (db.Date{Year: 2022, Month: time.August, Day: 19}); first != want {
+		t.Errorf("first live date = %v, want %v", first, want)
+	}
+	if want := (db.Date{Year: 2022, Month: time.August, Day: 21}); last != want {
+		t.Errorf("last live date = %v, want %v", last, want)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	dict, err := trie.New(strings.NewReader("detract\ncottage\n"))
+	if err != nil {
+		t.Fatalf("trie.New: %v", err)
+	}
+	s := &server{
+		channels:       map[string]*channel{db.DefaultChannel: testChannel(db.DefaultChannel, dict)},
+		db:             db.NewMemory(),
+		readyDaysAhead: 2,
+	}
+
+	if code := serveReadyz(s); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz with no games returned %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	first, last := liveDates(time.Now())
+	for d := first; d != last.AddDays(3); d = d.AddDays(1) {
+		if err := s.db.AddGame(db.DefaultChannel, d, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+			t.Fatalf("AddGame(%v): %v", d, err)
+		}
+	}
+	if code := serveReadyz(s); code != http.StatusOK {
+		t.Errorf("/readyz with games returned %d, want %d", code, http.StatusOK)
+	}
+
+	s.shuttingDown.Store(true)
+	if code := serveReadyz(s); code != http.StatusServiceUnavailable {
+		t.Errorf("/readyz while shutting down returned %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func serveReadyz(s *server) int {
+	w := httptest.NewRecorder()
+	s.serveReadyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	return w.Code
+}
@@ -2,31 +2,63 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 	"unicode/utf8"
 
+	"github.com/bcspragu/srordle/config"
 	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/gamestate"
+	"github.com/bcspragu/srordle/logging"
+	"github.com/bcspragu/srordle/metrics"
 	"github.com/bcspragu/srordle/srordle"
 	"github.com/bcspragu/srordle/trie"
+	"github.com/bcspragu/srordle/web"
+	"github.com/bcspragu/srordle/webhooks"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func main() {
 	if err := run(); err != nil {
-		log.Fatalf("run: %v", err)
+		// Printed directly instead of logged, since it may be a config error that
+		// happened before logging was set up, and is meant for a human.
+		fmt.Fprintf(os.Stderr, "run: %v\n", err)
+		os.Exit(1)
 	}
 }
 
 type Dictionary interface {
 	HasWord(in string) (bool, error)
+	// Size returns the number of words in the dictionary.
+	Size() int
+}
+
+// instrumentedDict records the latency of dictionary lookups.
+type instrumentedDict struct {
+	Dictionary
+}
+
+func (d instrumentedDict) HasWord(in string) (bool, error) {
+	start := time.Now()
+	defer func() { metrics.DictionaryLookupDuration.Observe(time.Since(start).Seconds()) }()
+	return d.Dictionary.HasWord(in)
 }
 
 type Answer struct {
@@ -35,121 +67,363 @@ type Answer struct {
 }
 
 type server struct {
-	dict           Dictionary
-	isLocal        bool
-	allTargetWords []string
-	r              *rand.Rand
-	db             *db.DB
+	// channels are the separate sets of games, by name.
+	channels map[string]*channel
+	assets   assetServer
+	r        *rand.Rand
+	db       db.Store
+	// games caches the games that are live now, and should be used instead of
+	// db for reading them.
+	games *gameCache
+
+	// stateCodec is nil if stateless game state tokens aren't enabled.
+	stateCodec *gamestate.Codec
+
+	// hooks sends webhook events, and is nil if no webhooks are configured.
+	hooks *webhooks.Dispatcher
+	// scheduleWarningDays is how many days of games a channel needs to have
+	// scheduled to not send schedule.low webhook events.
+	scheduleWarningDays int
+
+	// readyDaysAhead is how many days past today need to have games scheduled
+	// for the server to report itself as ready.
+	readyDaysAhead int
+	// baseURL is the configured scheme and host for links, or empty to use the
+	// request's.
+	baseURL string
+	// shuttingDown is set once the server starts shutting down, so load
+	// balancers stop sending it traffic.
+	shuttingDown atomic.Bool
 }
 
 func run() error {
 	var (
-		isLocal         = flag.Bool("local", true, "If true, serve /images and compiled artifacts")
-		dictPath        = flag.String("dictionary_path", "wordlists/dict.txt", "The file containing valid dictionary words.")
-		targetWordsPath = flag.String("target_words_path", "wordlists/target.txt", "The file containing solution words.")
-		dbDir           = flag.String("db_dir", ".badger", "The directory for the Badger database")
+		configPath = flag.String("config", os.Getenv(config.PathEnv), "If set, the path to a TOML config file. Flags that are explicitly set override settings in the file.")
+		flagCfg    = config.Default()
 	)
+	flagCfg.RegisterServerFlags(flag.CommandLine)
 	flag.Parse()
 
-	trie, err := loadTrie(*dictPath)
+	cfg, err := config.Load(*configPath)
 	if err != nil {
-		return fmt.Errorf("failed to load trie: %w", err)
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	if err := cfg.ApplyFlags(flag.CommandLine); err != nil {
+		return fmt.Errorf("failed to apply flags: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
 	}
 
-	targetWords, err := loadTargetWords(*targetWordsPath)
+	logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
 	if err != nil {
-		return fmt.Errorf("failed to load target words: %w", err)
+		return fmt.Errorf("failed to init logger: %w", err)
+	}
+	slog.SetDefault(logger)
+
+	var stateCodec *gamestate.Codec
+	if len(cfg.State.Keys) > 0 {
+		keys, err := gamestate.ParseKeys(strings.Join(cfg.State.Keys, ","))
+		if err != nil {
+			return fmt.Errorf("failed to parse state keys: %w", err)
+		}
+		if stateCodec, err = gamestate.NewCodec(keys, cfg.State.Encrypt); err != nil {
+			return fmt.Errorf("failed to init game state codec: %w", err)
+		}
+	}
+
+	channels, err := loadChannels(cfg)
+	if err != nil {
+		return err
+	}
+
+	var assets assetServer
+	if cfg.Server.Local {
+		assets = &diskAssets{dir: cfg.Server.AssetsDir}
+	} else {
+		ea, err := loadEmbeddedAssets(web.FS())
+		if err != nil {
+			return fmt.Errorf("failed to load embedded assets: %w", err)
+		}
+		if _, ok := ea.files["dist/index.html"]; !ok {
+			slog.Warn("the embedded assets don't include the frontend, was it built before the server?")
+		}
+		assets = ea
 	}
 
-	db, err := db.Open(*dbDir)
+	store, err := db.OpenStore(cfg.DB.Driver, cfg.DB.Path())
 	if err != nil {
 		return fmt.Errorf("failed to open database: %w", err)
 	}
-	defer db.Close()
+	defer store.Close()
 
 	srv := &server{
-		isLocal:        *isLocal,
-		dict:           trie,
-		allTargetWords: targetWords,
+		assets:         assets,
+		channels:       channels,
 		r:              rand.New(rand.NewSource(time.Now().UnixNano())),
-		db:             db,
+		db:             store,
+		games:          newGameCache(store),
+		stateCodec:     stateCodec,
+		readyDaysAhead: cfg.Server.ReadyDaysAhead,
+		baseURL:        strings.TrimSuffix(cfg.Server.BaseURL, "/"),
+	}
+	if len(cfg.Webhooks.URLs) > 0 {
+		srv.hooks = webhooks.New(webhooks.Options{
+			URLs:        cfg.Webhooks.URLs,
+			Secret:      []byte(cfg.Webhooks.Secret),
+			MaxAttempts: cfg.Webhooks.MaxAttempts,
+			Backoff:     cfg.Webhooks.Backoff,
+			Timeout:     cfg.Webhooks.Timeout,
+			DeadLetters: store,
+		})
+		srv.scheduleWarningDays = cfg.Webhooks.ScheduleWarningDays
 	}
 
 	mux := http.NewServeMux()
-	if *isLocal {
-		mux.HandleFunc("/", srv.serveHTML)
+	mux.Handle("/", metrics.InstrumentHandler("assets", srv.assets))
+	mux.Handle("/api/guess", metrics.InstrumentHandler("guess", http.HandlerFunc(srv.serveGuess)))
+	mux.Handle("/api/srordle", metrics.InstrumentHandler("srordle", http.HandlerFunc(srv.serveSrordle)))
+	mux.Handle(srordlePath, metrics.InstrumentHandler("srordle_by_date", http.HandlerFunc(srv.serveSrordleByDate)))
+	mux.Handle("/api/reveal", metrics.InstrumentHandler("reveal", http.HandlerFunc(srv.serveReveal)))
+	mux.Handle("/api/share", metrics.InstrumentHandler("share", http.HandlerFunc(srv.serveShare)))
+	mux.Handle(sharePath, metrics.InstrumentHandler("shared_result", http.HandlerFunc(srv.serveSharedResult)))
+	mux.Handle("/metrics", metrics.InstrumentHandler("metrics", metrics.Handler()))
+	mux.HandleFunc("/healthz", srv.serveHealthz)
+
+	auth := &adminAuth{token: cfg.Admin.Token, username: cfg.Admin.User, password: cfg.Admin.Password}
+	if auth.enabled() {
+		adminGames := metrics.InstrumentHandler("admin_games", auth.wrap(http.HandlerFunc(srv.serveAdminGames)))
+		mux.Handle(adminGamesPath, adminGames)
+		mux.Handle(adminGamesPath+"/", adminGames)
+		mux.Handle(adminReloadPath, metrics.InstrumentHandler("admin_reload", auth.wrap(http.HandlerFunc(srv.serveAdminReload))))
+		mux.Handle(adminDeadLettersPath, metrics.InstrumentHandler("admin_dead_letters", auth.wrap(http.HandlerFunc(srv.serveAdminDeadLetters))))
+		mux.Handle(dashboardPath, metrics.InstrumentHandler("admin_dashboard", auth.wrap(http.HandlerFunc(srv.serveDashboard))))
+	}
+	mux.Handle("/readyz", metrics.InstrumentHandler("readyz", http.HandlerFunc(srv.serveReadyz)))
+
+	// Only Badger needs its value log garbage collected.
+	bdb, isBadger := store.(*db.DB)
+	if isBadger {
+		metrics.RegisterDBSize(bdb.Size)
+	}
+
+	httpSrv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           logging.Middleware(recoverWrap(mux)),
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	if len(cfg.Server.AutocertHosts) > 0 {
+		httpSrv.TLSConfig = autocertConfig(cfg.Server.AutocertHosts, cfg.Server.AutocertCacheDir, cfg.Server.ACMEDirectoryURL)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	// Background work uses the database, so it needs to finish before the
+	// deferred Close above runs.
+	var wg sync.WaitGroup
+	defer func() {
+		stop()
+		wg.Wait()
+	}()
+
+	if isBadger {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runDBGC(ctx, bdb, cfg.DB.GCInterval)
+		}()
+	}
+
+	hupC := make(chan os.Signal, 1)
+	signal.Notify(hupC, syscall.SIGHUP)
+	defer signal.Stop(hupC)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		reloadWordsOnSignal(ctx, srv, hupC)
+	}()
+
+	// The dispatcher outlives the signal context, so events sent by handlers
+	// that are still being drained get delivered. It's stopped once they're
+	// done, or by the deferred cancel below if shutdown fails.
+	hooksCtx, stopHooks := context.WithCancel(context.Background())
+	defer stopHooks()
+	if srv.hooks != nil {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			srv.hooks.Run(hooksCtx)
+		}()
+		go func() {
+			defer wg.Done()
+			runWebhookEvents(ctx, srv, time.Minute)
+		}()
+	}
+
+	errC := make(chan error, 1)
+	go func() {
+		slog.Info("listening", "addr", cfg.Server.Addr)
+		errC <- listenAndServe(httpSrv, cfg.Server.TLSCert, cfg.Server.TLSKey)
+	}()
+
+	select {
+	case err := <-errC:
+		return fmt.Errorf("failed to serve: %w", err)
+	case <-ctx.Done():
 	}
-	mux.HandleFunc("/api/guess", srv.serveGuess)
-	mux.HandleFunc("/api/srordle", srv.serveSrordle)
 
-	if err := http.ListenAndServe(":8000", recoverWrap(mux)); err != nil {
-		return fmt.Errorf("http.ListenAndServe: %w", err)
+	// Restore the default signal behavior, so a second signal kills us.
+	stop()
+	srv.shuttingDown.Store(true)
+	slog.Info("shutting down, draining connections")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down server: %w", err)
 	}
+	stopHooks()
+
+	// The database is closed by the deferred Close above, now that no handlers
+	// are using it.
 	return nil
 }
 
-func (s *server) serveHTML(w http.ResponseWriter, r *http.Request) {
-	switch r.URL.Path {
-	case "/", "/index.html":
-		http.ServeFile(w, r, "dist/index.html")
-		return
-	case "/index.js":
-		http.ServeFile(w, r, "dist/index.js")
-		return
-	case "/index.css":
-		http.ServeFile(w, r, "dist/index.css")
-		return
-	}
+func runDBGC(ctx context.Context, db *db.DB, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
 
-	if s.isAsset(r.URL.Path) && s.isLocal {
-		http.ServeFile(w, r, strings.TrimPrefix(r.URL.Path, "/"))
-		return
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		// Badger recommends running GC repeatedly until nothing gets rewritten.
+		for {
+			rewritten, err := db.RunGC()
+			if err != nil {
+				slog.Error("failed to run database GC", "error", err)
+				metrics.DBGCTotal.WithLabelValues("error").Inc()
+				break
+			}
+			if !rewritten {
+				metrics.DBGCTotal.WithLabelValues("nothing_to_do").Inc()
+				break
+			}
+			metrics.DBGCTotal.WithLabelValues("rewritten").Inc()
+		}
 	}
+}
 
-	httpError(w, http.StatusNotFound, "path %q either not found or not allowed", r.URL.Path)
+func listenAndServe(srv *http.Server, certFile, keyFile string) error {
+	var err error
+	switch {
+	case certFile != "":
+		err = srv.ListenAndServeTLS(certFile, keyFile)
+	case srv.TLSConfig != nil:
+		// Certificates come from TLSConfig.GetCertificate.
+		err = srv.ListenAndServeTLS("", "")
+	default:
+		err = srv.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
-func (s *server) isAsset(p string) bool {
-	return strings.HasPrefix(p, "/images/")
+func autocertConfig(hosts []string, cacheDir, directoryURL string) *tls.Config {
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(hosts...),
+		Cache:      autocert.DirCache(cacheDir),
+	}
+	if directoryURL != "" {
+		m.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+	return m.TLSConfig()
 }
 
-func httpError(w http.ResponseWriter, code int, format string, args ...any) {
-	log.Printf(format, args...)
+// httpError logs the given message and attributes with the request's logger,
+// and responds with a generic error for the status code.
+func httpError(w http.ResponseWriter, r *http.Request, code int, msg string, args ...any) {
+	lvl := slog.LevelInfo
+	if code >= 500 {
+		lvl = slog.LevelError
+	}
+	logging.FromContext(r.Context()).Log(r.Context(), lvl, msg, append([]any{"status", code}, args...)...)
 	http.Error(w, http.StatusText(code), code)
 }
 
 func (s *server) serveGuess(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		httpError(w, http.StatusMethodNotAllowed, "invalid method %q", r.Method)
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
 		return
 	}
 
 	var req struct {
-		Guess    string `json:"guess"`
+		Guess string `json:"guess"`
+		// Date is the player's local date as YYYY-MM-DD, which picks the game. If
+		// it's empty, TZOffset, the player's offset in seconds east of UTC, is
+		// used to work it out instead.
+		Date     string `json:"date"`
 		TZOffset int    `json:"tzOffset"`
 
 		// Only one of these needs to be set.
 		GuessIndex int  `json:"guessIndex"`
 		UseFull    bool `json:"useFull"`
+
+		// State is the token from the previous guess, if stateless game state is
+		// enabled. It's empty for the first guess of the day.
+		State string `json:"state"`
+		// Channel is the channel being played, or empty for the default one.
+		Channel string `json:"channel"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httpError(w, http.StatusBadRequest, "failed to parse request: %v", err)
+		httpError(w, r, http.StatusBadRequest, "failed to parse request", "error", err)
 		return
 	}
 
-	gameDate := db.ToDate(time.Now().In(time.FixedZone("UserTZ", req.TZOffset)))
-	game, err := s.db.Game(gameDate)
+	ch, err := s.lookupChannel(req.Channel)
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "failed to load game: %v", err)
+		httpError(w, r, http.StatusNotFound, "unknown channel", "error", err)
 		return
 	}
 
-	errorRespf := func(fmtStr string, args ...interface{}) {
+	gameDate, err := requestDate(req.Date, req.TZOffset, time.Now())
+	if err != nil {
+		httpError(w, r, http.StatusBadRequest, "invalid game date", "error", err)
+		return
+	}
+	game, err := s.games.Game(ch.name, gameDate)
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", gameDate, "error", err)
+		return
+	}
+
+	errorRespf := func(outcome, fmtStr string, args ...interface{}) {
+		metrics.GuessesTotal.WithLabelValues(outcome).Inc()
 		jsonResp(w, struct {
 			Error string
 		}{fmt.Sprintf(fmtStr, args...)})
 	}
 
+	var state *gamestate.State
+	if s.stateCodec != nil {
+		var ok bool
+		if state, ok = s.loadState(w, r, req.State, ch, gameDate, game); !ok {
+			return
+		}
+		if err := game.CheckGuess(&state.Progress, req.GuessIndex, req.UseFull); err != nil {
+			errorRespf(metrics.OutcomeRejected, "%s", guessErrorMessage(err))
+			return
+		}
+	}
+
 	var (
 		targetWordLens []int
 		row            srordle.Row
@@ -157,7 +431,7 @@ func (s *server) serveGuess(w http.ResponseWriter, r *http.Request) {
 	)
 	req.Guess = strings.ToLower(req.Guess)
 	if req.UseFull || req.GuessIndex >= len(game.Shape) {
-		targetWordLens = []int{7}
+		targetWordLens = []int{srordle.WordLength}
 		row = srordle.Row([]bool{true, true, true, true, true, true, true})
 		guesses = []string{req.Guess}
 	} else if req.GuessIndex < len(game.Shape) {
@@ -166,29 +440,29 @@ func (s *server) serveGuess(w http.ResponseWriter, r *http.Request) {
 		var ok bool
 		guesses, ok = row.SplitGuess(req.Guess)
 		if !ok {
-			errorRespf("Your guess wasn't the right shape")
+			errorRespf(metrics.OutcomeWrongShape, "Your guess wasn't the right shape")
 			return
 		}
 	} else {
-		errorRespf("Invalid guess index given")
+		errorRespf(metrics.OutcomeRejected, "Invalid guess index given")
 		return
 	}
 
 	if len(guesses) != len(targetWordLens) {
-		errorRespf("Wanted %d guesses, got %d", len(targetWordLens), len(guesses))
+		errorRespf(metrics.OutcomeWrongShape, "Wanted %d guesses, got %d", len(targetWordLens), len(guesses))
 		return
 	}
 
 	var invalidWords []string
 	for i, guess := range guesses {
 		if utf8.RuneCountInString(guess) != targetWordLens[i] {
-			errorRespf("%s isn't %d letters long", guess, targetWordLens[i])
+			errorRespf(metrics.OutcomeWrongShape, "%s isn't %d letters long", guess, targetWordLens[i])
 			return
 		}
 
-		ok, err := s.dict.HasWord(guess)
+		ok, err := ch.dict.HasWord(guess)
 		if err != nil {
-			httpError(w, http.StatusInternalServerError, "failed to look in dictionary for %q: %v", guess, err)
+			httpError(w, r, http.StatusInternalServerError, "failed to look up guess in dictionary", "error", err)
 			return
 		}
 		if !ok {
@@ -200,43 +474,155 @@ func (s *server) serveGuess(w http.ResponseWriter, r *http.Request) {
 	case 0:
 		// All good
 	case 1:
-		errorRespf("%s isn't a word", strings.ToUpper(invalidWords[0]))
+		errorRespf(metrics.OutcomeInvalidWord, "%s isn't a word", strings.ToUpper(invalidWords[0]))
 		return
 	case 2:
-		errorRespf("Neither of those are real words")
+		errorRespf(metrics.OutcomeInvalidWord, "Neither of those are real words")
 		return
 	}
 
+	var stateTok string
+	if state != nil {
+		now := time.Now()
+		wasFinished := game.Finished(&state.Progress)
+		game.Record(&state.Progress, srordle.Guess{
+			Words:         guesses,
+			GuessedAt:     now,
+			RequestedFull: req.UseFull,
+		})
+		state.IssuedAt = now
+		// Only the guess that finishes the game sends the event and records the
+		// result, so each player is counted once.
+		if !wasFinished && game.Finished(&state.Progress) {
+			s.hooks.Send(webhooks.EventGameFinished, gameFinishedEvent{
+				Channel: ch.name,
+				Date:    gameDate.String(),
+				Won:     state.Progress.Won,
+				Guesses: len(state.Progress.Guesses),
+			})
+			if err := s.db.RecordResult(ch.name, gameDate, state.Progress.Won, len(state.Progress.Guesses)); err != nil {
+				slog.Error("failed to record game result", "channel", ch.name, "date", gameDate, "error", err)
+			}
+		}
+		if stateTok, err = s.stateCodec.Encode(state); err != nil {
+			httpError(w, r, http.StatusInternalServerError, "failed to encode game state", "error", err)
+			return
+		}
+	}
+
+	won := len(guesses) == 1 && guesses[0] == game.TargetWord
+	if won {
+		metrics.GuessesTotal.WithLabelValues(metrics.OutcomeWon).Inc()
+	} else {
+		metrics.GuessesTotal.WithLabelValues(metrics.OutcomeIncorrect).Inc()
+	}
+
 	jsonResp(w, struct {
 		Answer []srordle.LetterAnswer
 		Won    bool
 		Words  []string
+		State  string `json:",omitempty"`
 	}{
 		Answer: game.CalcAnswer(guesses, row),
-		Won:    len(guesses) == 1 && guesses[0] == game.TargetWord,
+		Won:    won,
 		Words:  guesses,
+		State:  stateTok,
 	})
 }
 
+// requestDate returns the live date a request for a game is for, from the
+// date it gives, or the player's offset in seconds east of UTC if it doesn't
+// give one.
+func requestDate(date string, tzOffset int, now time.Time) (db.Date, error) {
+	d := db.ToDate(now.In(time.FixedZone("UserTZ", tzOffset)))
+	if date != "" {
+		var err error
+		if d, err = db.ParseDate(date); err != nil {
+			return db.Date{}, err
+		}
+	}
+	if !isLive(d, now) {
+		return db.Date{}, fmt.Errorf("%s isn't today anywhere", d)
+	}
+	return d, nil
+}
+
+// loadState decodes the game state token sent by the client, or starts a new
+// one if the token is empty or from a previous day or another channel. If it
+// returns false, an error has already been written to the client.
+func (s *server) loadState(w http.ResponseWriter, r *http.Request, tok string, ch *channel, date db.Date, game *srordle.Game) (*gamestate.State, bool) {
+	newState := &gamestate.State{Channel: ch.name, Date: date, Progress: *game.NewProgress()}
+	if tok == "" {
+		return newState, true
+	}
+
+	state, err := s.stateCodec.Decode(tok)
+	if err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to decode game state", "error", err)
+		return nil, false
+	}
+	// Tokens for other days are replaced, including any for days that aren't
+	// live, which the server should never have issued.
+	if state.Date != date || stateChannel(state) != ch.name || !isLive(state.Date, time.Now()) {
+		return newState, true
+	}
+	return state, true
+}
+
+// stateChannel returns the channel of the game in the state, which is empty in
+// tokens for the default channel from before channels existed.
+func stateChannel(state *gamestate.State) string {
+	if state.Channel == "" {
+		return db.DefaultChannel
+	}
+	return state.Channel
+}
+
+func guessErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, srordle.ErrGameOver):
+		return "The game is already over"
+	case errors.Is(err, srordle.ErrWrongRow):
+		return "That guess was for the wrong row"
+	case errors.Is(err, srordle.ErrNoFullAttempts):
+		return "You don't have any 7-letter guesses left"
+	default:
+		return err.Error()
+	}
+}
+
 func (s *server) serveSrordle(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		httpError(w, http.StatusMethodNotAllowed, "invalid method %q", r.Method)
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
 		return
 	}
 
 	var req struct {
-		TZOffset int `json:"tzOffset"`
+		// Date and TZOffset pick the game like they do for serveGuess.
+		Date     string `json:"date"`
+		TZOffset int    `json:"tzOffset"`
+		Channel  string `json:"channel"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		httpError(w, http.StatusBadRequest, "failed to parse request: %v", err)
+		httpError(w, r, http.StatusBadRequest, "failed to parse request", "error", err)
+		return
+	}
+
+	ch, err := s.lookupChannel(req.Channel)
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "unknown channel", "error", err)
 		return
 	}
 
-	gameDate := db.ToDate(time.Now().In(time.FixedZone("UserTZ", req.TZOffset)))
-	game, err := s.db.Game(gameDate)
+	gameDate, err := requestDate(req.Date, req.TZOffset, time.Now())
 	if err != nil {
-		httpError(w, http.StatusInternalServerError, "failed to load game: %v", err)
+		httpError(w, r, http.StatusBadRequest, "invalid game date", "error", err)
+		return
+	}
+	game, err := s.games.Game(ch.name, gameDate)
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", gameDate, "error", err)
 		return
 	}
 
@@ -249,9 +635,84 @@ func (s *server) serveSrordle(w http.ResponseWriter, r *http.Request) {
 	}{game})
 }
 
+const srordlePath = "/api/srordle/"
+
+// sharedCacheMaxAge is the longest shared caches keep a day's game before
+// revalidating it.
+const sharedCacheMaxAge = time.Minute
+
+// serveSrordleByDate handles GET /api/srordle/YYYY-MM-DD?channel=<name>, which
+// returns the same thing as serveSrordle, but is cacheable until the next
+// rollover. The channel is optional, and defaults to the default channel.
+func (s *server) serveSrordleByDate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+
+	gameDate, err := db.ParseDate(strings.TrimPrefix(r.URL.Path, srordlePath))
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "invalid game date", "error", err)
+		return
+	}
+
+	ch, err := s.lookupChannel(r.URL.Query().Get("channel"))
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "unknown channel", "error", err)
+		return
+	}
+
+	// Don't hand out games before it's that day anywhere.
+	now := time.Now()
+	if _, last := liveDates(now); daysBetween(gameDate, last) < 0 {
+		httpError(w, r, http.StatusNotFound, "game isn't available yet", "date", gameDate)
+		return
+	}
+
+	game, err := s.games.Game(ch.name, gameDate)
+	if errors.Is(err, db.ErrGameNotFound) {
+		httpError(w, r, http.StatusNotFound, "no game for date", "channel", ch.name, "date", gameDate)
+		return
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", gameDate, "error", err)
+		return
+	}
+	game.TargetWord = ""
+
+	body, err := json.Marshal(struct {
+		Game *srordle.Game
+	}{game})
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to encode game", "error", err)
+		return
+	}
+	// The ETag is based on the response and not the whole stored game, since
+	// there aren't many target words, and it'd be easy to work backwards from a
+	// hash to the word.
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:8]) + `"`
+
+	// Browsers can keep the game until the rollover, but shared caches like
+	// CDNs check back sooner, so admin edits to a live game reach players
+	// who haven't loaded it yet.
+	expires := nextRollover(now)
+	maxAge := expires.Sub(now)
+	h := w.Header()
+	h.Set("Content-Type", "application/json")
+	h.Set("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", int(maxAge.Seconds()), int(min(maxAge, sharedCacheMaxAge).Seconds())))
+	h.Set("Expires", expires.Format(http.TimeFormat))
+	h.Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write(append(body, '\n'))
+}
+
 func jsonResp(w http.ResponseWriter, v interface{}) {
 	if err := json.NewEncoder(w).Encode(v); err != nil {
-		log.Printf("jsonResp: %v", err)
+		slog.Error("failed to encode JSON response", "error", err)
 	}
 }
 
@@ -306,9 +767,10 @@ func recoverWrap(h http.Handler) http.Handler {
 			default:
 				err = fmt.Errorf("unknown error had type %T: %v", r, r)
 			}
-			log.Printf("PANIC on handler %s: %v", r.URL.Path, err)
+			logging.FromContext(r.Context()).Error("panic in handler", "path", r.URL.Path, "error", err)
+			metrics.PanicsTotal.Inc()
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		}()
 		h.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
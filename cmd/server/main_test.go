@@ -1,82 +1,85 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
-	"github.com/google/go-cmp/cmp"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
 )
 
-func TestToTargetWordLengths(t *testing.T) {
-	shape := defaultShape()
-
-	wantPerRow := [][]int{
-		{7},
-		{4, 2},
-		{3, 3},
-		{2, 4},
-		{3},
-		{5},
-	}
-
-	for i, row := range shape {
-		got := row.toTargetWordLengths()
-		want := wantPerRow[i]
-		if diff := cmp.Diff(want, got); diff != "" {
-			t.Errorf("unexpected target word lengths (-want +got)\n%s", diff)
+func TestGuessOnlyLiveDates(t *testing.T) {
+	s := newAdminTestServer(t)
+	now := time.Now()
+	game := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}
+	for _, date := range []db.Date{db.ToDate(now.UTC()), db.ToDate(now.UTC()).AddDays(5)} {
+		if err := s.db.AddGame(db.DefaultChannel, date, game); err != nil {
+			t.Fatalf("AddGame: %v", err)
 		}
 	}
-}
 
-func TestSplitGuess(t *testing.T) {
-	shape := defaultShape()
-
-	inPerRow := []string{
-		"detract",
-		"testin",
-		"catdog",
-		"onstop",
-		"pet",
-		"guess",
+	post := func(handler http.HandlerFunc, req map[string]any) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body)))
+		return w
 	}
 
-	wantPerRow := [][]string{
-		{"detract"},
-		{"test", "in"},
-		{"cat", "dog"},
-		{"on", "stop"},
-		{"pet"},
-		{"guess"},
+	guess := map[string]any{"guess": "detract", "useFull": true, "tzOffset": 0}
+	if w := post(s.serveGuess, guess); w.Code != http.StatusOK {
+		t.Errorf("guess for today returned %d: %s", w.Code, w.Body)
+	}
+	if w := post(s.serveSrordle, map[string]any{"tzOffset": 0}); w.Code != http.StatusOK {
+		t.Errorf("srordle for today returned %d: %s", w.Code, w.Body)
 	}
 
-	for i, row := range shape {
-		got, ok := row.splitGuess(inPerRow[i])
-		if !ok {
-			t.Fatalf("row.splitGuess was not ok for %q", inPerRow[i])
-		}
-		want := wantPerRow[i]
-		if diff := cmp.Diff(want, got); diff != "" {
-			t.Errorf("unexpected target word lengths (-want +got)\n%s", diff)
-		}
+	// An offset that's days ahead would otherwise let the game be played early.
+	guess["tzOffset"] = 5 * 24 * 60 * 60
+	if w := post(s.serveGuess, guess); w.Code != http.StatusBadRequest {
+		t.Errorf("guess for a future date returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if w := post(s.serveSrordle, map[string]any{"tzOffset": 5 * 24 * 60 * 60}); w.Code != http.StatusBadRequest {
+		t.Errorf("srordle for a future date returned %d, want %d", w.Code, http.StatusBadRequest)
 	}
 }
 
-func TestToStartOffsets(t *testing.T) {
-	shape := defaultShape()
-
-	wantPerRow := [][]int{
-		{0},
-		{0, 5},
-		{0, 4},
-		{0, 3},
-		{2},
-		{1},
+func TestRequestDate(t *testing.T) {
+	// 20:00 in UTC-7 is already the next day in UTC.
+	now := time.Date(2022, time.August, 21, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		date     string
+		tzOffset int
+		want     db.Date
+		wantErr  bool
+	}{
+		{name: "offset west of UTC", tzOffset: -7 * 60 * 60, want: db.Date{Year: 2022, Month: time.August, Day: 20}},
+		{name: "UTC", want: db.Date{Year: 2022, Month: time.August, Day: 21}},
+		{name: "date wins over offset", date: "2022-08-20", want: db.Date{Year: 2022, Month: time.August, Day: 20}},
+		{name: "future date", date: "2022-08-23", wantErr: true},
+		{name: "past date", date: "2022-08-18", wantErr: true},
+		{name: "future offset", tzOffset: 3 * 24 * 60 * 60, wantErr: true},
+		{name: "invalid date", date: "tomorrow", wantErr: true},
 	}
-
-	for i, row := range shape {
-		got := row.toStartOffsets()
-		want := wantPerRow[i]
-		if diff := cmp.Diff(want, got); diff != "" {
-			t.Errorf("unexpected start offsets (-want +got)\n%s", diff)
-		}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := requestDate(test.date, test.tzOffset, now)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("requestDate returned %s, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("requestDate: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("requestDate = %s, want %s", got, test.want)
+			}
+		})
 	}
-}
\ No newline at end of file
+}
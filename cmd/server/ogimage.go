@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// The size recommended for Open Graph images.
+const (
+	ogImageWidth  = 1200
+	ogImageHeight = 630
+	ogImageMargin = 40
+	// ogTextScale is the size in pixels of each dot of the font.
+	ogTextScale = 10
+)
+
+// Colors match the board in the frontend, see src/lib/color.
+var (
+	ogBackground    = color.RGBA{0, 0, 0, 255}
+	ogText          = color.RGBA{255, 255, 255, 255}
+	ogCorrect       = color.RGBA{0, 204, 136, 255}
+	ogWrongPosition = color.RGBA{255, 204, 0, 255}
+	ogNotInWord     = color.RGBA{55, 65, 81, 255}
+)
+
+// glyphs is a tiny 5x7 bitmap font, with just what's needed for the puzzle
+// number.
+var glyphs = map[rune][glyphHeight]string{
+	'#': {".#.#.", ".#.#.", "#####", ".#.#.", "#####", ".#.#.", ".#.#."},
+	'0': {".###.", "#...#", "#..##", "#.#.#", "##..#", "#...#", ".###."},
+	'1': {"..#..", ".##..", "..#..", "..#..", "..#..", "..#..", ".###."},
+	'2': {".###.", "#...#", "....#", "...#.", "..#..", ".#...", "#####"},
+	'3': {"#####", "...#.", "..#..", "...#.", "....#", "#...#", ".###."},
+	'4': {"...#.", "..##.", ".#.#.", "#..#.", "#####", "...#.", "...#."},
+	'5': {"#####", "#....", "####.", "....#", "....#", "#...#", ".###."},
+	'6': {"..##.", ".#...", "#....", "####.", "#...#", "#...#", ".###."},
+	'7': {"#####", "....#", "...#.", "..#..", ".#...", ".#...", ".#..."},
+	'8': {".###.", "#...#", "#...#", ".###.", "#...#", "#...#", ".###."},
+	'9': {".###.", "#...#", "#...#", ".####", "....#", "...#.", ".##.."},
+}
+
+const (
+	glyphWidth  = 5
+	glyphHeight = 7
+)
+
+// boardLayout is where the cells of a board go in the image.
+type boardLayout struct {
+	x, y int
+	cell int
+	gap  int
+}
+
+// newBoardLayout fits a board with the given number of rows into the space
+// below the puzzle number, with square cells separated by gaps an eighth of
+// their size.
+func newBoardLayout(rows int) boardLayout {
+	if rows < 1 {
+		rows = 1
+	}
+	top := ogImageMargin + glyphHeight*ogTextScale + ogImageMargin
+	availW, availH := ogImageWidth-2*ogImageMargin, ogImageHeight-top-ogImageMargin
+
+	cell := min(availW*8/(srordle.WordLength*9-1), availH*8/(rows*9-1))
+	gap := cell / 8
+	width := srordle.WordLength*cell + (srordle.WordLength-1)*gap
+	return boardLayout{
+		x:    (ogImageWidth - width) / 2,
+		y:    top,
+		cell: cell,
+		gap:  gap,
+	}
+}
+
+// cellRect returns the bounds of the cell for the given guess and letter.
+func (l boardLayout) cellRect(row, col int) image.Rectangle {
+	x := l.x + col*(l.cell+l.gap)
+	y := l.y + row*(l.cell+l.gap)
+	return image.Rect(x, y, x+l.cell, y+l.cell)
+}
+
+// drawResult draws a shared result as an Open Graph image, with the puzzle
+// number across the top and a cell for each letter of each guess. Letters
+// aren't drawn, so the image doesn't give away the answer.
+func drawResult(number int, rows [][]srordle.LetterStatus) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, ogImageWidth, ogImageHeight))
+	draw.Draw(img, img.Bounds(), image.NewUniform(ogBackground), image.Point{}, draw.Src)
+
+	label := fmt.Sprintf("#%d", number)
+	drawText(img, label, (ogImageWidth-textWidth(label))/2, ogImageMargin)
+
+	l := newBoardLayout(len(rows))
+	for i, row := range rows {
+		for j, status := range row {
+			c, ok := statusColor(status)
+			if !ok {
+				continue
+			}
+			draw.Draw(img, l.cellRect(i, j), image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// statusColor returns the color of a cell with the given status, or false if
+// the cell should be left blank.
+func statusColor(s srordle.LetterStatus) (color.Color, bool) {
+	switch s {
+	case srordle.Correct:
+		return ogCorrect, true
+	case srordle.WrongPosition:
+		return ogWrongPosition, true
+	case srordle.NotInWord:
+		return ogNotInWord, true
+	default:
+		// Includes PositionNotUsed, the gaps in the shape.
+		return nil, false
+	}
+}
+
+func textWidth(s string) int {
+	n := len([]rune(s))
+	if n == 0 {
+		return 0
+	}
+	return (n*(glyphWidth+1) - 1) * ogTextScale
+}
+
+// drawText draws the string with its top left corner at x, y, skipping any
+// characters the font doesn't have.
+func drawText(img draw.Image, s string, x, y int) {
+	fg := image.NewUniform(ogText)
+	for _, r := range s {
+		g, ok := glyphs[r]
+		if ok {
+			for gy, line := range g {
+				for gx, dot := range line {
+					if dot != '#' {
+						continue
+					}
+					px, py := x+gx*ogTextScale, y+gy*ogTextScale
+					draw.Draw(img, image.Rect(px, py, px+ogTextScale, py+ogTextScale), fg, image.Point{}, draw.Src)
+				}
+			}
+		}
+		x += (glyphWidth + 1) * ogTextScale
+	}
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+)
+
+// serveReveal handles POST /api/reveal, which returns the target word and its
+// definition, but only once the game state token sent with the request shows
+// the game is over. Since the token is signed, players can't claim to be done
+// without having played.
+func (s *server) serveReveal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+	if s.stateCodec == nil {
+		httpError(w, r, http.StatusNotFound, "can't reveal answers without game state tokens")
+		return
+	}
+
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to parse request", "error", err)
+		return
+	}
+
+	state, err := s.stateCodec.Decode(req.State)
+	if err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to decode game state", "error", err)
+		return
+	}
+
+	// Tokens are only issued for live dates, but check again so a token for a
+	// future game can never reveal its answer early. Answers to past games are
+	// already out, so those are fine.
+	if _, last := liveDates(time.Now()); daysBetween(state.Date, last) < 0 {
+		httpError(w, r, http.StatusNotFound, "game isn't available yet", "date", state.Date)
+		return
+	}
+
+	ch, err := s.lookupChannel(state.Channel)
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "unknown channel", "error", err)
+		return
+	}
+
+	game, err := s.games.Game(ch.name, state.Date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		httpError(w, r, http.StatusNotFound, "no game for date", "channel", ch.name, "date", state.Date)
+		return
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", state.Date, "error", err)
+		return
+	}
+
+	if !game.Finished(&state.Progress) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		jsonResp(w, struct {
+			Error string
+		}{"The game isn't over yet"})
+		return
+	}
+
+	def := ch.words.Definition(game.TargetWord)
+	jsonResp(w, struct {
+		TargetWord   string
+		Won          bool
+		PartOfSpeech string `json:",omitempty"`
+		Definition   string `json:",omitempty"`
+	}{
+		TargetWord:   game.TargetWord,
+		Won:          state.Progress.Won,
+		PartOfSpeech: def.PartOfSpeech,
+		Definition:   def.Definition,
+	})
+}
+
+// Definition is what's shown to the player alongside the target word once
+// their game is over.
+type Definition struct {
+	PartOfSpeech string
+	Definition   string
+}
+
+// loadDefinitions reads a definitions file, see parseDefinitions for the
+// format. A missing file isn't an error, the game just won't show definitions.
+func loadDefinitions(fn string) (map[string]Definition, error) {
+	if fn == "" {
+		return map[string]Definition{}, nil
+	}
+	f, err := os.Open(fn)
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Warn("definitions file doesn't exist, answers will be revealed without definitions", "path", fn)
+		return map[string]Definition{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to open definitions file: %w", err)
+	}
+	defer f.Close()
+
+	return parseDefinitions(f)
+}
+
+// parseDefinitions reads tab-separated lines of <word>, <part of speech>,
+// <definition>. Blank lines and lines starting with # are ignored.
+func parseDefinitions(r io.Reader) (map[string]Definition, error) {
+	out := make(map[string]Definition)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		txt := strings.TrimSpace(sc.Text())
+		if txt == "" || strings.HasPrefix(txt, "#") {
+			continue
+		}
+		parts := strings.Split(txt, "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("line %d: expected 3 tab-separated fields, got %d", line, len(parts))
+		}
+		word := strings.ToLower(strings.TrimSpace(parts[0]))
+		if word == "" {
+			return nil, fmt.Errorf("line %d: missing word", line)
+		}
+		out[word] = Definition{
+			PartOfSpeech: strings.TrimSpace(parts[1]),
+			Definition:   strings.TrimSpace(parts[2]),
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan definitions: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/gamestate"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseDefinitions(t *testing.T) {
+	in := "# A comment\n\ndetract\tverb\tTo diminish the worth of something.\nCottage\tnoun\tA small house.\n"
+	got, err := parseDefinitions(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("parseDefinitions: %v", err)
+	}
+	want := map[string]Definition{
+		"detract": {PartOfSpeech: "verb", Definition: "To diminish the worth of something."},
+		"cottage": {PartOfSpeech: "noun", Definition: "A small house."},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected definitions (-want +got)\n%s", diff)
+	}
+
+	if _, err := parseDefinitions(strings.NewReader("detract\tverb\n")); err == nil {
+		t.Error("parseDefinitions with a missing field didn't return an error")
+	}
+}
+
+func TestReveal(t *testing.T) {
+	s := newAdminTestServer(t)
+	codec, err := gamestate.NewCodec([]gamestate.Key{{ID: "1", Secret: bytes.Repeat([]byte{1}, 32)}}, false)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	s.stateCodec = codec
+	s.channels[db.DefaultChannel].words = staticWordStore(&wordLists{
+		definitions: map[string]Definition{
+			"detract": {PartOfSpeech: "verb", Definition: "To diminish the worth of something."},
+		},
+	})
+
+	date := db.Date{Year: 2022, Month: time.August, Day: 20}
+	game := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 1}
+	if err := s.db.AddGame(db.DefaultChannel, date, game); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	reveal := func(state *gamestate.State) *httptest.ResponseRecorder {
+		tok, err := codec.Encode(state)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		body, _ := json.Marshal(map[string]string{"state": tok})
+		w := httptest.NewRecorder()
+		s.serveReveal(w, httptest.NewRequest(http.MethodPost, "/api/reveal", bytes.NewReader(body)))
+		return w
+	}
+
+	state := &gamestate.State{Date: date, Progress: *game.NewProgress()}
+	if w := reveal(state); w.Code != http.StatusForbidden || strings.Contains(w.Body.String(), "detract") {
+		t.Errorf("reveal before finishing returned %d: %s", w.Code, w.Body)
+	}
+
+	game.Record(&state.Progress, srordle.Guess{Words: []string{"cottage"}, RequestedFull: true})
+	w := reveal(state)
+	if w.Code != http.StatusOK {
+		t.Fatalf("reveal after finishing returned %d: %s", w.Code, w.Body)
+	}
+	var got struct {
+		TargetWord   string
+		Won          bool
+		PartOfSpeech string
+		Definition   string
+	}
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if got.TargetWord != "detract" || got.Won || got.PartOfSpeech != "verb" || got.Definition == "" {
+		t.Errorf("unexpected reveal response %+v", got)
+	}
+
+	// Finished tokens for games that aren't live anywhere yet don't reveal them.
+	future := db.ToDate(time.Now().UTC()).AddDays(5)
+	if err := s.db.AddGame(db.DefaultChannel, future, game); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	futureState := &gamestate.State{Date: future, Progress: state.Progress}
+	if w := reveal(futureState); w.Code != http.StatusNotFound || strings.Contains(w.Body.String(), "detract") {
+		t.Errorf("reveal for a future game returned %d: %s", w.Code, w.Body)
+	}
+
+	// Tokens that weren't signed by the server are rejected.
+	other, err := gamestate.NewCodec([]gamestate.Key{{ID: "1", Secret: bytes.Repeat([]byte{2}, 32)}}, false)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	forged, err := other.Encode(state)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	w = httptest.NewRecorder()
+	s.serveReveal(w, httptest.NewRequest(http.MethodPost, "/api/reveal", strings.NewReader(`{"state":"`+forged+`"}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("reveal with forged token returned %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
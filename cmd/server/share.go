@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"image/png"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// sharePath serves shared results, at <sharePath><id> for the page and
+// <sharePath><id>.png for its image.
+const sharePath = "/share/"
+
+// errNoPuzzleNumber is returned by puzzleNumber for dates before the channel's
+// first game.
+var errNoPuzzleNumber = errors.New("date is before the channel's first game")
+
+// puzzleNumber returns the number of the puzzle on the given date in the
+// channel, where its first scheduled game is #1.
+func (s *server) puzzleNumber(channel string, d db.Date) (int, error) {
+	var (
+		first   db.Date
+		found   bool
+		errStop = errors.New("stop")
+	)
+	err := s.db.EachGame(channel, db.Date{}, d, func(dg db.DatedGame) error {
+		first, found = dg.Date, true
+		return errStop
+	})
+	if err != nil && !errors.Is(err, errStop) {
+		return 0, fmt.Errorf("failed to find the first game: %w", err)
+	}
+	if !found {
+		return 0, errNoPuzzleNumber
+	}
+	return daysBetween(first, d) + 1, nil
+}
+
+var shareIDRE = regexp.MustCompile(`^[0-9a-f]{24}$`)
+
+// shareID derives the ID of a shared result from the player's final game state
+// token, so sharing the same game again gives the same URL, and the ID can't
+// be guessed without the token.
+func shareID(stateTok string) string {
+	sum := sha256.Sum256([]byte(stateTok))
+	return hex.EncodeToString(sum[:12])
+}
+
+// serveShare saves a finished game for sharing, and returns the URLs of the
+// shared page and its image.
+func (s *server) serveShare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+	if s.stateCodec == nil {
+		httpError(w, r, http.StatusNotFound, "can't share results without game state tokens")
+		return
+	}
+
+	var req struct {
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to parse request", "error", err)
+		return
+	}
+
+	state, err := s.stateCodec.Decode(req.State)
+	if err != nil {
+		httpError(w, r, http.StatusBadRequest, "failed to decode game state", "error", err)
+		return
+	}
+
+	ch, err := s.lookupChannel(state.Channel)
+	if err != nil {
+		httpError(w, r, http.StatusNotFound, "unknown channel", "error", err)
+		return
+	}
+
+	game, err := s.games.Game(ch.name, state.Date)
+	if errors.Is(err, db.ErrGameNotFound) {
+		httpError(w, r, http.StatusNotFound, "no game for date", "channel", ch.name, "date", state.Date)
+		return
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load game", "channel", ch.name, "date", state.Date, "error", err)
+		return
+	}
+
+	if !game.Finished(&state.Progress) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		jsonResp(w, struct {
+			Error string
+		}{"The game isn't over yet"})
+		return
+	}
+
+	res := &db.SharedResult{
+		Channel:   ch.name,
+		Date:      state.Date,
+		Won:       state.Progress.Won,
+		CreatedAt: time.Now(),
+	}
+	for _, row := range game.Board(&state.Progress) {
+		var statuses []srordle.LetterStatus
+		for _, la := range row {
+			statuses = append(statuses, la.Status)
+		}
+		res.Rows = append(res.Rows, statuses)
+	}
+
+	id := shareID(req.State)
+	if err := s.db.AddShare(id, res); err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to save shared result", "error", err)
+		return
+	}
+
+	jsonResp(w, struct {
+		URL      string
+		ImageURL string
+	}{
+		URL:      sharePath + id,
+		ImageURL: sharePath + id + ".png",
+	})
+}
+
+// serveSharedResult serves the page for a shared result, which is the game
+// with Open Graph tags for the result, or the result's image.
+func (s *server) serveSharedResult(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		httpError(w, r, http.StatusMethodNotAllowed, "invalid method", "method", r.Method)
+		return
+	}
+
+	id, isImage := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, sharePath), ".png")
+	if !shareIDRE.MatchString(id) {
+		httpError(w, r, http.StatusNotFound, "invalid share ID", "path", r.URL.Path)
+		return
+	}
+
+	res, err := s.db.Share(id)
+	if errors.Is(err, db.ErrShareNotFound) {
+		httpError(w, r, http.StatusNotFound, "shared result not found", "id", id)
+		return
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load shared result", "id", id, "error", err)
+		return
+	}
+
+	// Results can only be shared for scheduled games, so this only fails if the
+	// game and every one before it have since been deleted.
+	num, err := s.puzzleNumber(res.Channel, res.Date)
+	if errors.Is(err, errNoPuzzleNumber) {
+		httpError(w, r, http.StatusNotFound, "shared result has no puzzle number", "id", id, "channel", res.Channel, "date", res.Date)
+		return
+	} else if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to number shared result", "id", id, "error", err)
+		return
+	}
+
+	if isImage {
+		s.serveShareImage(w, r, num, res)
+	} else {
+		s.serveSharePage(w, r, id, num, res)
+	}
+}
+
+func (s *server) serveShareImage(w http.ResponseWriter, r *http.Request, num int, res *db.SharedResult) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, drawResult(num, res.Rows)); err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to encode image", "error", err)
+		return
+	}
+	h := w.Header()
+	h.Set("Content-Type", "image/png")
+	// Shared results never change.
+	h.Set("Cache-Control", "public, max-age=86400, immutable")
+	h.Set("Content-Length", fmt.Sprint(buf.Len()))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(buf.Bytes())
+}
+
+func (s *server) serveSharePage(w http.ResponseWriter, r *http.Request, id string, num int, res *db.SharedResult) {
+	page, err := s.assets.ReadAsset("dist/index.html")
+	if err != nil {
+		httpError(w, r, http.StatusInternalServerError, "failed to load index.html", "error", err)
+		return
+	}
+
+	origin := s.origin(r)
+	page = injectOGTags(page, []ogTag{
+		{"og:title", fmt.Sprintf("Srordle #%d", num)},
+		{"og:description", shareDescription(res)},
+		{"og:type", "website"},
+		{"og:url", origin + sharePath + id},
+		{"og:image", origin + sharePath + id + ".png"},
+		{"og:image:width", fmt.Sprint(ogImageWidth)},
+		{"og:image:height", fmt.Sprint(ogImageHeight)},
+		{"twitter:card", "summary_large_image"},
+	})
+
+	h := w.Header()
+	h.Set("Content-Type", "text/html; charset=utf-8")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Content-Length", fmt.Sprint(len(page)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(page)
+}
+
+func shareDescription(res *db.SharedResult) string {
+	if !res.Won {
+		return "Didn't get it this time."
+	}
+	if len(res.Rows) == 1 {
+		return "Got it in 1 guess!"
+	}
+	return fmt.Sprintf("Got it in %d guesses!", len(res.Rows))
+}
+
+// origin returns the scheme and host for links, which is the configured base
+// URL if there is one, and otherwise the one the request was made to.
+func (s *server) origin(r *http.Request) string {
+	if s.baseURL != "" {
+		return s.baseURL
+	}
+	return requestOrigin(r)
+}
+
+// requestOrigin returns the scheme and host the request was made to, taking
+// into account a TLS-terminating proxy in front of the server.
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+type ogTag struct {
+	property string
+	content  string
+}
+
+var ogMetaRE = regexp.MustCompile(`[ \t]*<meta property="og:[^"]*"[^>]*>\n?`)
+
+// injectOGTags replaces the Open Graph tags in the page with the given ones.
+// It also sets the base URL to the root, since the page is served from under
+// sharePath, but links to its scripts and styles relative to the root.
+func injectOGTags(page []byte, tags []ogTag) []byte {
+	page = ogMetaRE.ReplaceAll(page, nil)
+
+	var b bytes.Buffer
+	b.WriteString("  <base href=\"/\">\n")
+	for _, t := range tags {
+		attr := "property"
+		if strings.HasPrefix(t.property, "twitter:") {
+			attr = "name"
+		}
+		fmt.Fprintf(&b, "  <meta %s=\"%s\" content=\"%s\">\n", attr, html.EscapeString(t.property), html.EscapeString(t.content))
+	}
+	b.WriteString("</head>")
+
+	return bytes.Replace(page, []byte("</head>"), b.Bytes(), 1)
+}
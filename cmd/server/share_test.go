@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/gamestate"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+const testIndexHTML = `<!doctype html>
+<html lang="en">
+<head>
+  <title>Srordle</title>
+  <meta property="og:title" content="Srordle">
+  <meta property="og:url" content="https://srini.igotyouapresent.com">
+</head>
+<body><script src="index.js"></script></body>
+</html>
+`
+
+func TestShare(t *testing.T) {
+	s := newAdminTestServer(t)
+	codec, err := gamestate.NewCodec([]gamestate.Key{{ID: "1", Secret: bytes.Repeat([]byte{1}, 32)}}, false)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	s.stateCodec = codec
+	ea, err := loadEmbeddedAssets(fstest.MapFS{"dist/index.html": {Data: []byte(testIndexHTML)}})
+	if err != nil {
+		t.Fatalf("loadEmbeddedAssets: %v", err)
+	}
+	s.assets = ea
+
+	date := db.Date{Year: 2022, Month: time.August, Day: 20}
+	game := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 1}
+	if err := s.db.AddGame(db.DefaultChannel, date, game); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	share := func(state *gamestate.State) *httptest.ResponseRecorder {
+		tok, err := codec.Encode(state)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		body, _ := json.Marshal(map[string]string{"state": tok})
+		w := httptest.NewRecorder()
+		s.serveShare(w, httptest.NewRequest(http.MethodPost, "/api/share", bytes.NewReader(body)))
+		return w
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.serveSharedResult(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	state := &gamestate.State{Date: date, Progress: *game.NewProgress()}
+	game.Record(&state.Progress, srordle.Guess{Words: []string{"cottage"}})
+	if w := share(state); w.Code != http.StatusForbidden {
+		t.Errorf("share before finishing returned %d: %s", w.Code, w.Body)
+	}
+
+	game.Record(&state.Progress, srordle.Guess{Words: []string{"detract"}, RequestedFull: true})
+	w := share(state)
+	if w.Code != http.StatusOK {
+		t.Fatalf("share after finishing returned %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		URL      string
+		ImageURL string
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	w = get(resp.URL)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s returned %d", resp.URL, w.Code)
+	}
+	page := w.Body.String()
+	for _, want := range []string{
+		`<meta property="og:title" content="Srordle #1">`,
+		`<meta property="og:image" content="http://example.com` + resp.ImageURL + `">`,
+		`<meta name="twitter:card" content="summary_large_image">`,
+		`<base href="/">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("shared page doesn't contain %s:\n%s", want, page)
+		}
+	}
+	if strings.Contains(page, "srini.igotyouapresent.com") {
+		t.Errorf("shared page still has the original og:url:\n%s", page)
+	}
+	if strings.Contains(page, "detract") {
+		t.Error("shared page includes the target word")
+	}
+
+	// With a base URL configured, links don't use the host the client sent.
+	s.baseURL = "https://srordle.example.com"
+	r := httptest.NewRequest(http.MethodGet, resp.URL, nil)
+	r.Host = "evil.example.com"
+	r.Header.Set("X-Forwarded-Proto", "http")
+	w = httptest.NewRecorder()
+	s.serveSharedResult(w, r)
+	page = w.Body.String()
+	if want := `<meta property="og:url" content="https://srordle.example.com` + resp.URL + `">`; !strings.Contains(page, want) {
+		t.Errorf("shared page doesn't contain %s:\n%s", want, page)
+	}
+	if strings.Contains(page, "evil.example.com") {
+		t.Errorf("shared page links to the request's host:\n%s", page)
+	}
+	s.baseURL = ""
+
+	w = get(resp.ImageURL)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s returned %d", resp.ImageURL, w.Code)
+	}
+	img, err := png.Decode(w.Body)
+	if err != nil {
+		t.Fatalf("failed to decode image: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != ogImageWidth || b.Dy() != ogImageHeight {
+		t.Errorf("image is %dx%d, want %dx%d", b.Dx(), b.Dy(), ogImageWidth, ogImageHeight)
+	}
+
+	for _, path := range []string{sharePath + "0123456789abcdef01234567", sharePath + "not-an-id", sharePath + "../index.html"} {
+		if w := get(path); w.Code != http.StatusNotFound {
+			t.Errorf("GET %s returned %d, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+}
+
+func TestDrawResult(t *testing.T) {
+	rows := [][]srordle.LetterStatus{
+		{srordle.NotInWord, srordle.WrongPosition, srordle.PositionNotUsed, srordle.Correct, srordle.Correct, srordle.NotInWord, srordle.Correct},
+		{srordle.Correct, srordle.Correct, srordle.Correct, srordle.Correct, srordle.Correct, srordle.Correct, srordle.Correct},
+	}
+	img := drawResult(1, rows)
+
+	l := newBoardLayout(len(rows))
+	if last := l.cellRect(len(rows)-1, srordle.WordLength-1); !last.In(img.Bounds()) {
+		t.Fatalf("last cell %v is outside the image %v", last, img.Bounds())
+	}
+
+	tests := []struct {
+		row, col int
+		want     any
+	}{
+		{0, 0, ogNotInWord},
+		{0, 1, ogWrongPosition},
+		// Gaps in the shape are left blank.
+		{0, 2, ogBackground},
+		{0, 3, ogCorrect},
+		{1, 6, ogCorrect},
+	}
+	for _, test := range tests {
+		r := l.cellRect(test.row, test.col)
+		mid := r.Min.Add(r.Size().Div(2))
+		if got := img.RGBAAt(mid.X, mid.Y); got != test.want {
+			t.Errorf("cell (%d, %d) is %v, want %v", test.row, test.col, got, test.want)
+		}
+	}
+}
+
+func TestPuzzleNumber(t *testing.T) {
+	s := newAdminTestServer(t)
+	game := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 1}
+	for _, g := range []struct {
+		channel string
+		date    db.Date
+	}{
+		{db.DefaultChannel, db.Date{Year: 2022, Month: time.August, Day: 1}},
+		{db.DefaultChannel, db.Date{Year: 2022, Month: time.August, Day: 20}},
+		{"other", db.Date{Year: 2023, Month: time.March, Day: 5}},
+	} {
+		if err := s.db.AddGame(g.channel, g.date, game); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+
+	tests := []struct {
+		channel string
+		date    db.Date
+		want    int
+	}{
+		{db.DefaultChannel, db.Date{Year: 2022, Month: time.August, Day: 1}, 1},
+		{db.DefaultChannel, db.Date{Year: 2022, Month: time.August, Day: 20}, 20},
+		{"other", db.Date{Year: 2023, Month: time.March, Day: 5}, 1},
+		{"other", db.Date{Year: 2023, Month: time.March, Day: 7}, 3},
+	}
+	for _, test := range tests {
+		got, err := s.puzzleNumber(test.channel, test.date)
+		if err != nil {
+			t.Errorf("puzzleNumber(%q, %s): %v", test.channel, test.date, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("puzzleNumber(%q, %s) = %d, want %d", test.channel, test.date, got, test.want)
+		}
+	}
+
+	for _, ch := range []string{db.DefaultChannel, "other", "empty"} {
+		if _, err := s.puzzleNumber(ch, db.Date{Year: 2022, Month: time.July, Day: 31}); !errors.Is(err, errNoPuzzleNumber) {
+			t.Errorf("puzzleNumber(%q) before the first game returned %v, want %v", ch, err, errNoPuzzleNumber)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/webhooks"
+)
+
+const adminDeadLettersPath = "/api/admin/dead-letters"
+
+// Data sent with each webhook event.
+type (
+	gameLiveEvent struct {
+		Channel      string
+		Date         string
+		FullAttempts int
+	}
+
+	gameFinishedEvent struct {
+		Channel string
+		Date    string
+		Won     bool
+		Guesses int
+	}
+
+	scheduleLowEvent struct {
+		Channel string
+		// DaysScheduled is how many days in a row have games, starting with the
+		// date that just went live.
+		DaysScheduled int
+		// FirstMissing is the first date without a game.
+		FirstMissing string
+	}
+)
+
+// runWebhookEvents sends game.live and schedule.low events each time a new date
+// goes live somewhere in the world, checking every interval until the context
+// is cancelled. Dates that were already live when it started aren't announced,
+// so restarts don't repeat events.
+func runWebhookEvents(ctx context.Context, s *server, interval time.Duration) {
+	_, announced := liveDates(time.Now())
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+
+		_, last := liveDates(time.Now())
+		for daysBetween(announced, last) > 0 {
+			announced = announced.AddDays(1)
+			s.announceDate(announced)
+		}
+	}
+}
+
+// announceDate sends the events for a date that just went live, for every
+// channel.
+func (s *server) announceDate(date db.Date) {
+	for _, name := range s.channelNames() {
+		g, err := s.games.Game(name, date)
+		switch {
+		case err == nil:
+			s.hooks.Send(webhooks.EventGameLive, gameLiveEvent{
+				Channel:      name,
+				Date:         date.String(),
+				FullAttempts: g.FullAttempts,
+			})
+		case errors.Is(err, db.ErrGameNotFound):
+			// Reported by schedule.low below.
+		default:
+			slog.Error("failed to load game for webhook", "channel", name, "date", date, "error", err)
+			continue
+		}
+
+		if err := s.checkSchedule(name, date); err != nil {
+			slog.Error("failed to check schedule for webhook", "channel", name, "date", date, "error", err)
+		}
+	}
+}
+
+// checkSchedule sends schedule.low if the channel doesn't have games for the
+// configured number of days, starting with the given date.
+func (s *server) checkSchedule(channel string, from db.Date) error {
+	if s.scheduleWarningDays <= 0 {
+		return nil
+	}
+	games, err := s.db.Games(channel, from, from.AddDays(s.scheduleWarningDays-1))
+	if err != nil {
+		return err
+	}
+	// Games are in date order, so the first missing date is the first one that
+	// doesn't line up.
+	for i := 0; i < s.scheduleWarningDays; i++ {
+		d := from.AddDays(i)
+		if i < len(games) && games[i].Date == d {
+			continue
+		}
+		s.hooks.Send(webhooks.EventScheduleLow, scheduleLowEvent{
+			Channel:       channel,
+			DaysScheduled: i,
+			FirstMissing:  d.String(),
+		})
+		return nil
+	}
+	return nil
+}
+
+// deadLetter is a db.DeadLetter with the payload as JSON instead of bytes.
+type deadLetter struct {
+	ID        string
+	URL       string
+	Event     string
+	Payload   json.RawMessage
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+// serveAdminDeadLetters lists webhook deliveries that were given up on.
+func (s *server) serveAdminDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		adminError(w, r, http.StatusMethodNotAllowed, "method %s not allowed", r.Method)
+		return
+	}
+
+	dls, err := s.db.DeadLetters()
+	if err != nil {
+		adminError(w, r, http.StatusInternalServerError, "failed to load dead letters: %v", err)
+		return
+	}
+	out := []deadLetter{}
+	for _, dl := range dls {
+		out = append(out, deadLetter{
+			ID:        dl.ID,
+			URL:       dl.URL,
+			Event:     dl.Event,
+			Payload:   json.RawMessage(dl.Payload),
+			Attempts:  dl.Attempts,
+			LastError: dl.LastError,
+			FailedAt:  dl.FailedAt,
+		})
+	}
+	jsonResp(w, struct {
+		DeadLetters []deadLetter
+	}{out})
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/gamestate"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/webhooks"
+)
+
+func TestAnnounceDate(t *testing.T) {
+	secret := []byte("test-secret")
+	recv := make(chan webhooks.Payload, 10)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+			return
+		}
+		if !webhooks.Verify(secret, r.Header.Get(webhooks.TimestampHeader), body, r.Header.Get(webhooks.SignatureHeader)) {
+			t.Error("delivery had an invalid signature")
+		}
+		var p webhooks.Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		recv <- p
+	}))
+	defer hookSrv.Close()
+
+	s := newAdminTestServer(t)
+	s.hooks = webhooks.New(webhooks.Options{URLs: []string{hookSrv.URL}, Secret: secret})
+	s.scheduleWarningDays = 3
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.hooks.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	date := db.Date{Year: 2022, Month: time.August, Day: 20}
+	for _, d := range []db.Date{date, date.AddDays(1)} {
+		if err := s.db.AddGame(db.DefaultChannel, d, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+
+	s.announceDate(date)
+
+	got := make(map[string]map[string]any)
+	for i := 0; i < 2; i++ {
+		select {
+		case p := <-recv:
+			got[p.Event], _ = p.Data.(map[string]any)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for webhooks, got %v", got)
+		}
+	}
+
+	if live := got[webhooks.EventGameLive]; live["Channel"] != db.DefaultChannel || live["Date"] != "2022-08-20" {
+		t.Errorf("unexpected %s data %v", webhooks.EventGameLive, live)
+	}
+	// Only two of the three days are scheduled.
+	if low := got[webhooks.EventScheduleLow]; low["DaysScheduled"] != 2.0 || low["FirstMissing"] != "2022-08-22" {
+		t.Errorf("unexpected %s data %v", webhooks.EventScheduleLow, low)
+	}
+}
+
+func TestGameFinishedOnce(t *testing.T) {
+	recv := make(chan webhooks.Payload, 10)
+	hookSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p webhooks.Payload
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		recv <- p
+	}))
+	defer hookSrv.Close()
+
+	s := newAdminTestServer(t)
+	// A single worker delivers events in the order they're sent.
+	s.hooks = webhooks.New(webhooks.Options{URLs: []string{hookSrv.URL}, Workers: 1})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.hooks.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	date := db.ToDate(time.Now().UTC())
+	if err := s.db.AddGame(db.DefaultChannel, date, &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	guess := func(state string) string {
+		body, _ := json.Marshal(map[string]any{"guess": "detract", "useFull": true, "date": date.String(), "state": state})
+		w := httptest.NewRecorder()
+		s.serveGuess(w, httptest.NewRequest(http.MethodPost, "/api/guess", bytes.NewReader(body)))
+		var resp struct{ State string }
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("failed to decode guess response: %v", err)
+		}
+		return resp.State
+	}
+	next := func() webhooks.Payload {
+		select {
+		case p := <-recv:
+			return p
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for webhook")
+		}
+		return webhooks.Payload{}
+	}
+
+	// Without game state, a win could be replayed, so nothing is sent.
+	guess("")
+	s.hooks.Send("marker", nil)
+	if p := next(); p.Event != "marker" {
+		t.Errorf("got %q event without game state, want none", p.Event)
+	}
+
+	codec, err := gamestate.NewCodec([]gamestate.Key{{ID: "1", Secret: bytes.Repeat([]byte{1}, 32)}}, false)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	s.stateCodec = codec
+	state := guess("")
+	if p := next(); p.Event != webhooks.EventGameFinished {
+		t.Errorf("got %q event for the winning guess, want %q", p.Event, webhooks.EventGameFinished)
+	} else if data, _ := p.Data.(map[string]any); data["Won"] != true || data["Guesses"] != 1.0 {
+		t.Errorf("unexpected %s data %v", webhooks.EventGameFinished, data)
+	}
+	stats, err := s.db.Stats(db.DefaultChannel, date)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Played != 1 || stats.Won != 1 {
+		t.Errorf("got %d played and %d won, want 1 of each", stats.Played, stats.Won)
+	}
+
+	// Guessing again with the finished game's state doesn't send it or record
+	// the result again.
+	guess(state)
+	s.hooks.Send("marker", nil)
+	if p := next(); p.Event != "marker" {
+		t.Errorf("got %q event for a finished game, want none", p.Event)
+	}
+	if stats, err := s.db.Stats(db.DefaultChannel, date); err != nil {
+		t.Fatalf("Stats: %v", err)
+	} else if stats.Played != 1 {
+		t.Errorf("got %d played after guessing on a finished game, want 1", stats.Played)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bcspragu/srordle/metrics"
+	"github.com/bcspragu/srordle/trie"
+)
+
+// wordPaths are the files the word lists are loaded from.
+type wordPaths struct {
+	dict        string
+	targetWords string
+	definitions string
+}
+
+// wordLists are the word lists loaded from disk. They're never modified once
+// loaded, and get replaced as a whole on reload.
+type wordLists struct {
+	dict        *trie.Trie
+	targetWords []string
+	definitions map[string]Definition
+}
+
+func loadWordLists(paths wordPaths) (*wordLists, error) {
+	dict, err := loadTrie(paths.dict)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load trie: %w", err)
+	}
+	// An empty dictionary is almost certainly a file that's mid-write, and
+	// would reject every guess.
+	if dict.Size() == 0 {
+		return nil, fmt.Errorf("dictionary %q has no words", paths.dict)
+	}
+
+	targetWords, err := loadTargetWords(paths.targetWords)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load target words: %w", err)
+	}
+	if len(targetWords) == 0 {
+		return nil, fmt.Errorf("target word list %q has no words", paths.targetWords)
+	}
+
+	definitions, err := loadDefinitions(paths.definitions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load definitions: %w", err)
+	}
+
+	return &wordLists{
+		dict:        dict,
+		targetWords: targetWords,
+		definitions: definitions,
+	}, nil
+}
+
+// wordStore is a Dictionary whose word lists can be reloaded from disk while
+// the server is running. Readers always see a complete set of lists, either
+// the old ones or the new ones.
+type wordStore struct {
+	paths wordPaths
+	cur   atomic.Pointer[wordLists]
+
+	// reloadMu keeps reloads from running concurrently.
+	reloadMu sync.Mutex
+}
+
+func newWordStore(paths wordPaths) (*wordStore, error) {
+	lists, err := loadWordLists(paths)
+	if err != nil {
+		return nil, err
+	}
+	ws := &wordStore{paths: paths}
+	ws.cur.Store(lists)
+	return ws, nil
+}
+
+func (ws *wordStore) HasWord(in string) (bool, error) {
+	return ws.cur.Load().dict.HasWord(in)
+}
+
+func (ws *wordStore) Size() int {
+	return ws.cur.Load().dict.Size()
+}
+
+// Definition returns the definition of the given word, which is empty if we
+// don't have one.
+func (ws *wordStore) Definition(word string) Definition {
+	return ws.cur.Load().definitions[word]
+}
+
+// TargetWords returns the list of possible target words, which must not be
+// modified.
+func (ws *wordStore) TargetWords() []string {
+	return ws.cur.Load().targetWords
+}
+
+// listDiff describes how a word list changed on reload.
+type listDiff struct {
+	Words   int
+	Added   int
+	Removed int
+}
+
+func (d listDiff) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("words", d.Words),
+		slog.Int("added", d.Added),
+		slog.Int("removed", d.Removed),
+	)
+}
+
+type reloadResult struct {
+	Dictionary  listDiff
+	TargetWords listDiff
+	Definitions int
+}
+
+// Reload loads the word lists from disk and swaps them in. If loading fails,
+// the current lists are kept.
+func (ws *wordStore) Reload() (*reloadResult, error) {
+	ws.reloadMu.Lock()
+	defer ws.reloadMu.Unlock()
+
+	next, err := loadWordLists(ws.paths)
+	if err != nil {
+		metrics.WordListReloadsTotal.WithLabelValues("error").Inc()
+		return nil, err
+	}
+
+	prev := ws.cur.Load()
+	res := &reloadResult{
+		Dictionary:  diffDicts(prev.dict, next.dict),
+		TargetWords: diffWords(prev.targetWords, next.targetWords),
+		Definitions: len(next.definitions),
+	}
+	ws.cur.Store(next)
+	metrics.WordListReloadsTotal.WithLabelValues("success").Inc()
+	return res, nil
+}
+
+func diffDicts(prev, next *trie.Trie) listDiff {
+	d := listDiff{Words: next.Size()}
+	next.Walk(func(word string) {
+		if ok, _ := prev.HasWord(word); !ok {
+			d.Added++
+		}
+	})
+	prev.Walk(func(word string) {
+		if ok, _ := next.HasWord(word); !ok {
+			d.Removed++
+		}
+	})
+	return d
+}
+
+func diffWords(prev, next []string) listDiff {
+	prevSet, nextSet := toSet(prev), toSet(next)
+	d := listDiff{Words: len(nextSet)}
+	for w := range nextSet {
+		if _, ok := prevSet[w]; !ok {
+			d.Added++
+		}
+	}
+	for w := range prevSet {
+		if _, ok := nextSet[w]; !ok {
+			d.Removed++
+		}
+	}
+	return d
+}
+
+func toSet(words []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(words))
+	for _, w := range words {
+		out[w] = struct{}{}
+	}
+	return out
+}
+
+// reloadWordsOnSignal reloads the word lists whenever a signal is received on
+// sigC, until the context is cancelled.
+func reloadWordsOnSignal(ctx context.Context, s *server, sigC <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigC:
+		}
+
+		slog.Info("reloading word lists")
+		logReload(slog.Default(), "reloaded word lists", s.reloadWords())
+	}
+}
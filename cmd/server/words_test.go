@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWordStoreReload(t *testing.T) {
+	dir := t.TempDir()
+	paths := wordPaths{
+		dict:        filepath.Join(dir, "dict.txt"),
+		targetWords: filepath.Join(dir, "target.txt"),
+		definitions: filepath.Join(dir, "definitions.tsv"),
+	}
+	write := func(fn, contents string) {
+		t.Helper()
+		if err := os.WriteFile(fn, []byte(contents), 0600); err != nil {
+			t.Fatalf("failed to write %s: %v", fn, err)
+		}
+	}
+	write(paths.dict, "cat\ndetract\ncottage\n")
+	write(paths.targetWords, "detract\ncottage\n")
+
+	ws, err := newWordStore(paths)
+	if err != nil {
+		t.Fatalf("newWordStore: %v", err)
+	}
+	assertHasWord := func(word string, want bool) {
+		t.Helper()
+		got, err := ws.HasWord(word)
+		if err != nil {
+			t.Fatalf("HasWord(%q): %v", word, err)
+		}
+		if got != want {
+			t.Errorf("HasWord(%q) = %t, want %t", word, got, want)
+		}
+	}
+	assertHasWord("cat", true)
+	assertHasWord("dog", false)
+
+	write(paths.dict, "detract\ncottage\ndog\nbat\n")
+	write(paths.targetWords, "detract\nbattery\n")
+	write(paths.definitions, "detract\tverb\tTo diminish the worth of something.\n")
+	res, err := ws.Reload()
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	want := &reloadResult{
+		Dictionary:  listDiff{Words: 4, Added: 2, Removed: 1},
+		TargetWords: listDiff{Words: 2, Added: 1, Removed: 1},
+		Definitions: 1,
+	}
+	if diff := cmp.Diff(want, res); diff != "" {
+		t.Errorf("unexpected reload result (-want +got)\n%s", diff)
+	}
+	assertHasWord("cat", false)
+	assertHasWord("dog", true)
+	if def := ws.Definition("detract"); def.PartOfSpeech != "verb" {
+		t.Errorf("unexpected definition after reload %+v", def)
+	}
+
+	// A broken load keeps the old lists.
+	write(paths.dict, "")
+	if _, err := ws.Reload(); err == nil {
+		t.Error("Reload with an empty dictionary didn't return an error")
+	}
+	if err := os.Remove(paths.targetWords); err != nil {
+		t.Fatalf("failed to remove target words: %v", err)
+	}
+	write(paths.dict, "cat\n")
+	if _, err := ws.Reload(); err == nil {
+		t.Error("Reload with missing target words didn't return an error")
+	}
+	assertHasWord("dog", true)
+	if got := ws.Size(); got != 4 {
+		t.Errorf("Size() = %d after failed reloads, want 4", got)
+	}
+}
+
+// staticWordStore returns a wordStore with the given lists, which can't be
+// reloaded.
+func staticWordStore(lists *wordLists) *wordStore {
+	ws := &wordStore{}
+	ws.cur.Store(lists)
+	return ws
+}
@@ -0,0 +1,518 @@
+// Package config loads settings shared by the server and the CLI. Settings
+// come from, in increasing order of precedence: defaults, a TOML config file,
+// SRORDLE_* environment variables, and command-line flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/gamestate"
+	"github.com/bcspragu/srordle/logging"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// PathEnv is the environment variable holding the path to the config file,
+// used when one isn't given on the command line.
+const PathEnv = "SRORDLE_CONFIG"
+
+type Config struct {
+	Server Server `toml:"server"`
+	Words  Words  `toml:"words"`
+	DB     DB     `toml:"db"`
+	Log    Log    `toml:"log"`
+	Admin  Admin  `toml:"admin"`
+	State  State  `toml:"state"`
+
+	Webhooks Webhooks `toml:"webhooks"`
+
+	// Channels configures separate sets of games, keyed by channel name. The
+	// default channel always exists, and can be configured here too.
+	Channels map[string]Channel `toml:"channels"`
+}
+
+type Server struct {
+	Addr string `toml:"addr" env:"SRORDLE_SERVER_ADDR"`
+	// Local serves the frontend from AssetsDir instead of the embedded copy.
+	Local     bool   `toml:"local" env:"SRORDLE_SERVER_LOCAL"`
+	AssetsDir string `toml:"assets_dir" env:"SRORDLE_SERVER_ASSETS_DIR"`
+
+	TLSCert          string   `toml:"tls_cert" env:"SRORDLE_SERVER_TLS_CERT"`
+	TLSKey           string   `toml:"tls_key" env:"SRORDLE_SERVER_TLS_KEY"`
+	AutocertHosts    []string `toml:"autocert_hosts" env:"SRORDLE_SERVER_AUTOCERT_HOSTS"`
+	AutocertCacheDir string   `toml:"autocert_cache_dir" env:"SRORDLE_SERVER_AUTOCERT_CACHE_DIR"`
+	ACMEDirectoryURL string   `toml:"acme_directory_url" env:"SRORDLE_SERVER_ACME_DIRECTORY_URL"`
+
+	ReadTimeout       time.Duration `toml:"read_timeout" env:"SRORDLE_SERVER_READ_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `toml:"read_header_timeout" env:"SRORDLE_SERVER_READ_HEADER_TIMEOUT"`
+	WriteTimeout      time.Duration `toml:"write_timeout" env:"SRORDLE_SERVER_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `toml:"idle_timeout" env:"SRORDLE_SERVER_IDLE_TIMEOUT"`
+	ShutdownTimeout   time.Duration `toml:"shutdown_timeout" env:"SRORDLE_SERVER_SHUTDOWN_TIMEOUT"`
+
+	ReadyDaysAhead int `toml:"ready_days_ahead" env:"SRORDLE_SERVER_READY_DAYS_AHEAD"`
+
+	// BaseURL is the scheme and host players reach the server at, like
+	// https://srordle.example.com, used for links in shared results. If it's
+	// empty, links use the host and scheme the request was made with, which
+	// clients can set to anything.
+	BaseURL string `toml:"base_url" env:"SRORDLE_SERVER_BASE_URL"`
+}
+
+type Words struct {
+	DictionaryPath  string `toml:"dictionary_path" env:"SRORDLE_WORDS_DICTIONARY_PATH"`
+	TargetWordsPath string `toml:"target_words_path" env:"SRORDLE_WORDS_TARGET_WORDS_PATH"`
+	// DefinitionsPath is a file of definitions shown when the answer is
+	// revealed. It's optional, and answers are revealed without definitions if
+	// it's empty or missing.
+	DefinitionsPath string `toml:"definitions_path" env:"SRORDLE_WORDS_DEFINITIONS_PATH"`
+}
+
+type DB struct {
+	// Driver is "badger" or "sqlite". Badger can only be opened by one process
+	// at a time, so use SQLite to change games with the CLI while the server is
+	// running.
+	Driver string `toml:"driver" env:"SRORDLE_DB_DRIVER"`
+	// Dir is the Badger database directory.
+	Dir string `toml:"dir" env:"SRORDLE_DB_DIR"`
+	// SQLitePath is the SQLite database file.
+	SQLitePath string        `toml:"sqlite_path" env:"SRORDLE_DB_SQLITE_PATH"`
+	GCInterval time.Duration `toml:"gc_interval" env:"SRORDLE_DB_GC_INTERVAL"`
+}
+
+// Path returns where the database is for its driver.
+func (d DB) Path() string {
+	if d.Driver == db.DriverSQLite {
+		return d.SQLitePath
+	}
+	return d.Dir
+}
+
+type Log struct {
+	Level  string `toml:"level" env:"SRORDLE_LOG_LEVEL"`
+	Format string `toml:"format" env:"SRORDLE_LOG_FORMAT"`
+}
+
+type Admin struct {
+	Token    string `toml:"token" env:"SRORDLE_ADMIN_TOKEN"`
+	User     string `toml:"user" env:"SRORDLE_ADMIN_USER"`
+	Password string `toml:"password" env:"SRORDLE_ADMIN_PASSWORD"`
+}
+
+type State struct {
+	// Keys are <id>:<hex secret> pairs, see gamestate.ParseKeys.
+	Keys    []string `toml:"keys" env:"SRORDLE_STATE_KEYS"`
+	Encrypt bool     `toml:"encrypt" env:"SRORDLE_STATE_ENCRYPT"`
+}
+
+// Webhooks are URLs that get a signed POST when a game goes live, a player
+// finishes, or a channel's schedule is about to run out, see the webhooks
+// package.
+type Webhooks struct {
+	URLs []string `toml:"urls" env:"SRORDLE_WEBHOOKS_URLS"`
+	// Secret signs every payload, and is required if any URLs are set.
+	Secret      string        `toml:"secret" env:"SRORDLE_WEBHOOKS_SECRET"`
+	MaxAttempts int           `toml:"max_attempts" env:"SRORDLE_WEBHOOKS_MAX_ATTEMPTS"`
+	Backoff     time.Duration `toml:"backoff" env:"SRORDLE_WEBHOOKS_BACKOFF"`
+	Timeout     time.Duration `toml:"timeout" env:"SRORDLE_WEBHOOKS_TIMEOUT"`
+	// ScheduleWarningDays is how many days of games, starting with the newest
+	// live one, a channel needs to have scheduled to not send schedule.low.
+	ScheduleWarningDays int `toml:"schedule_warning_days" env:"SRORDLE_WEBHOOKS_SCHEDULE_WARNING_DAYS"`
+}
+
+// Channel overrides the word lists and game settings for one channel. Unset
+// settings fall back to those in [words] and the game defaults.
+type Channel struct {
+	DictionaryPath  string `toml:"dictionary_path"`
+	TargetWordsPath string `toml:"target_words_path"`
+	DefinitionsPath string `toml:"definitions_path"`
+	// Shape is the shape of new games, with one string per row, e.g.
+	// "xxx.xxx", see srordle.ParseShape.
+	Shape        []string `toml:"shape"`
+	FullAttempts *int     `toml:"full_attempts"`
+}
+
+// GameShape returns the shape for new games in the channel.
+func (c Channel) GameShape() (srordle.Shape, error) {
+	if len(c.Shape) == 0 {
+		return srordle.DefaultShape(), nil
+	}
+	return srordle.ParseShape(c.Shape)
+}
+
+// GameFullAttempts returns the number of full attempts for new games in the
+// channel.
+func (c Channel) GameFullAttempts() int {
+	if c.FullAttempts == nil {
+		return srordle.DefaultFullAttempts
+	}
+	return *c.FullAttempts
+}
+
+// ChannelNames returns the names of every channel, starting with the default
+// one.
+func (c *Config) ChannelNames() []string {
+	names := []string{db.DefaultChannel}
+	for name := range c.Channels {
+		if name != db.DefaultChannel {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// Channel returns the settings for the given channel, with anything it doesn't
+// set filled in from [words]. It returns false if there's no such channel.
+func (c *Config) Channel(name string) (Channel, bool) {
+	ch, ok := c.Channels[name]
+	if !ok && name != db.DefaultChannel {
+		return Channel{}, false
+	}
+	if ch.DictionaryPath == "" {
+		ch.DictionaryPath = c.Words.DictionaryPath
+	}
+	if ch.TargetWordsPath == "" {
+		ch.TargetWordsPath = c.Words.TargetWordsPath
+	}
+	if ch.DefinitionsPath == "" {
+		ch.DefinitionsPath = c.Words.DefinitionsPath
+	}
+	return ch, true
+}
+
+// Default returns the configuration used when nothing else is specified.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":8000",
+			Local:             true,
+			AssetsDir:         "web",
+			AutocertCacheDir:  ".autocert",
+			ReadTimeout:       10 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   15 * time.Second,
+			ReadyDaysAhead:    3,
+		},
+		Words: Words{
+			DictionaryPath:  "wordlists/dict.txt",
+			TargetWordsPath: "wordlists/target.txt",
+			DefinitionsPath: "wordlists/definitions.tsv",
+		},
+		DB: DB{
+			Driver:     db.DriverBadger,
+			Dir:        ".badger",
+			SQLitePath: "srordle.db",
+			GCInterval: 10 * time.Minute,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+		Webhooks: Webhooks{
+			MaxAttempts:         5,
+			Backoff:             5 * time.Second,
+			Timeout:             10 * time.Second,
+			ScheduleWarningDays: 7,
+		},
+	}
+}
+
+// Load returns the default configuration, overridden by the TOML file at path
+// (if path isn't empty) and then by environment variables.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		md, err := toml.DecodeFile(path, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file %q: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			var keys []string
+			for _, k := range undecoded {
+				keys = append(keys, k.String())
+			}
+			return nil, fmt.Errorf("unknown settings in config file %q: %s", path, strings.Join(keys, ", "))
+		}
+	}
+
+	if err := applyEnv(reflect.ValueOf(cfg).Elem(), os.LookupEnv); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// applyEnv sets each field with an env tag from the corresponding environment
+// variable, if it's set.
+func applyEnv(v reflect.Value, lookup func(string) (string, bool)) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.Type.Kind() == reflect.Struct {
+			if err := applyEnv(fv, lookup); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		val, ok := lookup(name)
+		if !ok {
+			continue
+		}
+		if err := setValue(fv, val); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// setValue parses the string into the given field. Lists are comma-separated.
+func setValue(fv reflect.Value, val string) error {
+	switch fv.Interface().(type) {
+	case string:
+		fv.SetString(val)
+	case bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case int:
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(n))
+	case time.Duration:
+		d, err := time.ParseDuration(val)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+	case []string:
+		fv.Set(reflect.ValueOf(splitList(val)))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func splitList(in string) []string {
+	var out []string
+	for _, v := range strings.Split(in, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate checks the configuration for mistakes, returning all of them.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Addr == "" {
+		add("server.addr must be set")
+	}
+	if c.Server.Local && c.Server.AssetsDir == "" {
+		add("server.assets_dir must be set when server.local is true")
+	}
+	if (c.Server.TLSCert == "") != (c.Server.TLSKey == "") {
+		add("server.tls_cert and server.tls_key must be set together")
+	}
+	if c.Server.TLSCert != "" && len(c.Server.AutocertHosts) > 0 {
+		add("server.tls_cert and server.autocert_hosts can't both be set")
+	}
+	if len(c.Server.AutocertHosts) > 0 && c.Server.AutocertCacheDir == "" {
+		add("server.autocert_cache_dir must be set when using server.autocert_hosts")
+	}
+	durations := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"db.gc_interval", c.DB.GCInterval},
+		{"webhooks.backoff", c.Webhooks.Backoff},
+		{"webhooks.timeout", c.Webhooks.Timeout},
+	}
+	for _, d := range durations {
+		if d.d <= 0 {
+			add("%s must be positive, was %s", d.name, d.d)
+		}
+	}
+	if c.Server.ReadyDaysAhead < 0 {
+		add("server.ready_days_ahead can't be negative, was %d", c.Server.ReadyDaysAhead)
+	}
+	if c.Server.BaseURL != "" {
+		if parsed, err := url.Parse(c.Server.BaseURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" || strings.Trim(parsed.Path, "/") != "" {
+			add("server.base_url: %q isn't an http or https URL without a path", c.Server.BaseURL)
+		}
+	}
+
+	if c.Words.DictionaryPath == "" {
+		add("words.dictionary_path must be set")
+	}
+	if c.Words.TargetWordsPath == "" {
+		add("words.target_words_path must be set")
+	}
+	switch c.DB.Driver {
+	case db.DriverBadger:
+		if c.DB.Dir == "" {
+			add("db.dir must be set")
+		}
+	case db.DriverSQLite:
+		if c.DB.SQLitePath == "" {
+			add("db.sqlite_path must be set")
+		}
+	default:
+		add("db.driver must be one of %s, was %q", strings.Join(db.Drivers, ", "), c.DB.Driver)
+	}
+
+	for _, name := range c.ChannelNames() {
+		if err := db.ValidateChannel(name); err != nil {
+			add("channels: %v", err)
+			continue
+		}
+		ch, _ := c.Channel(name)
+		if _, err := ch.GameShape(); err != nil {
+			add("channels.%s.shape: %v", name, err)
+		}
+		if n := ch.GameFullAttempts(); n < 0 {
+			add("channels.%s.full_attempts can't be negative, was %d", name, n)
+		}
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		add("log.level: %v", err)
+	}
+	if f := strings.ToLower(c.Log.Format); f != "text" && f != "json" {
+		add("log.format must be text or json, was %q", c.Log.Format)
+	}
+
+	if (c.Admin.User == "") != (c.Admin.Password == "") {
+		add("admin.user and admin.password must be set together")
+	}
+
+	if len(c.State.Keys) > 0 {
+		keys, err := gamestate.ParseKeys(strings.Join(c.State.Keys, ","))
+		if err == nil {
+			_, err = gamestate.NewCodec(keys, c.State.Encrypt)
+		}
+		if err != nil {
+			add("state.keys: %v", err)
+		}
+	} else if c.State.Encrypt {
+		add("state.encrypt requires state.keys to be set")
+	}
+
+	for _, u := range c.Webhooks.URLs {
+		if parsed, err := url.Parse(u); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			add("webhooks.urls: %q isn't an http or https URL", u)
+		}
+	}
+	if len(c.Webhooks.URLs) > 0 && c.Webhooks.Secret == "" {
+		add("webhooks.secret must be set when using webhooks.urls")
+	}
+	if c.Webhooks.MaxAttempts < 1 {
+		add("webhooks.max_attempts must be at least 1, was %d", c.Webhooks.MaxAttempts)
+	}
+	if c.Webhooks.ScheduleWarningDays < 0 {
+		add("webhooks.schedule_warning_days can't be negative, was %d", c.Webhooks.ScheduleWarningDays)
+	}
+
+	return errors.Join(errs...)
+}
+
+// RegisterServerFlags binds the server's command-line flags to c, using its
+// current values as defaults.
+func (c *Config) RegisterServerFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Server.Local, "local", c.Server.Local, "If true, serve the frontend and images from -assets_dir on disk instead of the copy embedded in the binary")
+	fs.StringVar(&c.Server.AssetsDir, "assets_dir", c.Server.AssetsDir, "The directory containing the compiled frontend (under dist/) and images, used when -local is set")
+	fs.StringVar(&c.Words.DictionaryPath, "dictionary_path", c.Words.DictionaryPath, "The file containing valid dictionary words.")
+	fs.StringVar(&c.Words.TargetWordsPath, "target_words_path", c.Words.TargetWordsPath, "The file containing solution words.")
+	fs.StringVar(&c.Words.DefinitionsPath, "definitions_path", c.Words.DefinitionsPath, "The file containing definitions of solution words, shown when the answer is revealed.")
+	fs.StringVar(&c.DB.Driver, "db_driver", c.DB.Driver, "The database to use, badger or sqlite")
+	fs.StringVar(&c.DB.Dir, "db_dir", c.DB.Dir, "The directory for the Badger database")
+	fs.StringVar(&c.DB.SQLitePath, "db_sqlite_path", c.DB.SQLitePath, "The file for the SQLite database")
+	fs.Var((*listValue)(&c.State.Keys), "state_keys", "If set, a comma-separated list of <id>:<hex secret> keys for signing game state tokens, the first of which signs new tokens. Secrets must be at least 32 bytes.")
+	fs.BoolVar(&c.State.Encrypt, "encrypt_state", c.State.Encrypt, "If true, game state tokens are encrypted in addition to being signed")
+
+	fs.StringVar(&c.Server.Addr, "addr", c.Server.Addr, "The address to listen on")
+	fs.StringVar(&c.Server.TLSCert, "tls_cert", c.Server.TLSCert, "If set, the path to a TLS certificate to serve HTTPS with. Requires -tls_key.")
+	fs.StringVar(&c.Server.TLSKey, "tls_key", c.Server.TLSKey, "If set, the path to the TLS private key for -tls_cert.")
+	fs.Var((*listValue)(&c.Server.AutocertHosts), "autocert_hosts", "If set, a comma-separated list of hosts to automatically get TLS certificates for via ACME. Can't be used with -tls_cert.")
+	fs.StringVar(&c.Server.AutocertCacheDir, "autocert_cache_dir", c.Server.AutocertCacheDir, "The directory to cache ACME certificates in")
+	fs.StringVar(&c.Server.ACMEDirectoryURL, "acme_directory_url", c.Server.ACMEDirectoryURL, "If set, the ACME directory to get certificates from, e.g. a local Pebble instance. Defaults to Let's Encrypt.")
+	fs.DurationVar(&c.Server.ReadTimeout, "read_timeout", c.Server.ReadTimeout, "The maximum duration for reading an entire request")
+	fs.DurationVar(&c.Server.ReadHeaderTimeout, "read_header_timeout", c.Server.ReadHeaderTimeout, "The maximum duration for reading request headers")
+	fs.DurationVar(&c.Server.WriteTimeout, "write_timeout", c.Server.WriteTimeout, "The maximum duration before timing out writes of a response")
+	fs.DurationVar(&c.Server.IdleTimeout, "idle_timeout", c.Server.IdleTimeout, "The maximum time to wait for the next request on a keep-alive connection")
+	fs.DurationVar(&c.Server.ShutdownTimeout, "shutdown_timeout", c.Server.ShutdownTimeout, "How long to wait for in-flight requests to finish when shutting down")
+	fs.DurationVar(&c.DB.GCInterval, "db_gc_interval", c.DB.GCInterval, "How often to run garbage collection on the database")
+	fs.IntVar(&c.Server.ReadyDaysAhead, "ready_days_ahead", c.Server.ReadyDaysAhead, "How many days past today need to have games scheduled for /readyz to report ready")
+	fs.StringVar(&c.Server.BaseURL, "base_url", c.Server.BaseURL, "If set, the URL players reach the server at, like https://srordle.example.com, used for links in shared results. Defaults to the host of each request.")
+
+	fs.StringVar(&c.Admin.Token, "admin_token", c.Admin.Token, "If set, a bearer token that grants access to the admin API")
+	fs.StringVar(&c.Admin.User, "admin_user", c.Admin.User, "If set along with -admin_password, a username for HTTP basic auth to the admin API and dashboard")
+	fs.StringVar(&c.Admin.Password, "admin_password", c.Admin.Password, "The password for -admin_user")
+
+	fs.StringVar(&c.Log.Level, "log_level", c.Log.Level, "The minimum level to log at, one of debug, info, warn, or error")
+	fs.StringVar(&c.Log.Format, "log_format", c.Log.Format, "The format to log in, either text or json")
+}
+
+// ApplyFlags copies the flags that were explicitly set in fs, which must have
+// been registered with RegisterServerFlags, onto c.
+func (c *Config) ApplyFlags(fs *flag.FlagSet) error {
+	target := flag.NewFlagSet("", flag.ContinueOnError)
+	c.RegisterServerFlags(target)
+
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil || target.Lookup(f.Name) == nil {
+			return
+		}
+		if setErr := target.Set(f.Name, f.Value.String()); setErr != nil {
+			err = fmt.Errorf("invalid value for -%s: %w", f.Name, setErr)
+		}
+	})
+	return err
+}
+
+// listValue is a flag.Value for comma-separated lists.
+type listValue []string
+
+func (l *listValue) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listValue) Set(v string) error {
+	*l = splitList(v)
+	return nil
+}
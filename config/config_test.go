@@ -0,0 +1,183 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+[server]
+addr = ":9000"
+autocert_hosts = ["a.example.com", "b.example.com"]
+read_timeout = "30s"
+
+[db]
+dir = "/data/badger"
+`)
+	t.Setenv("SRORDLE_DB_DIR", "/env/badger")
+	t.Setenv("SRORDLE_SERVER_READY_DAYS_AHEAD", "7")
+	t.Setenv("SRORDLE_STATE_KEYS", "2:aa, 1:bb")
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	want := Default()
+	want.Server.Addr = ":9000"
+	want.Server.AutocertHosts = []string{"a.example.com", "b.example.com"}
+	want.Server.ReadTimeout = 30 * time.Second
+	want.Server.ReadyDaysAhead = 7
+	want.DB.Dir = "/env/badger"
+	want.State.Keys = []string{"2:aa", "1:bb"}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected config (-want +got)\n%s", diff)
+	}
+}
+
+func TestLoadUnknownSetting(t *testing.T) {
+	path := writeConfig(t, `
+[server]
+adddr = ":9000"
+`)
+	_, err := Load(path)
+	if err == nil || !strings.Contains(err.Error(), "server.adddr") {
+		t.Errorf("Load with a typo returned %v, want an error mentioning server.adddr", err)
+	}
+}
+
+func TestApplyFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	Default().RegisterServerFlags(fs)
+	if err := fs.Parse([]string{"-addr", ":1234", "-autocert_hosts", "x.example.com,y.example.com"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	cfg := Default()
+	cfg.Server.Addr = ":9000"
+	cfg.DB.Dir = "/from/file"
+	if err := cfg.ApplyFlags(fs); err != nil {
+		t.Fatalf("ApplyFlags: %v", err)
+	}
+
+	if cfg.Server.Addr != ":1234" {
+		t.Errorf("addr = %q, want the flag value :1234", cfg.Server.Addr)
+	}
+	if diff := cmp.Diff([]string{"x.example.com", "y.example.com"}, cfg.Server.AutocertHosts); diff != "" {
+		t.Errorf("unexpected autocert hosts (-want +got)\n%s", diff)
+	}
+	// Flags that weren't set don't override the file.
+	if cfg.DB.Dir != "/from/file" {
+		t.Errorf("db dir = %q, want the file value /from/file", cfg.DB.Dir)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Errorf("default config was invalid: %v", err)
+	}
+
+	cfg := Default()
+	cfg.Server.TLSCert = "cert.pem"
+	cfg.Log.Level = "loud"
+	cfg.Admin.User = "admin"
+	cfg.State.Encrypt = true
+	cfg.Webhooks.URLs = []string{"ftp://example.com/hook"}
+	cfg.DB.Driver = "postgres"
+	cfg.Server.BaseURL = "srordle.example.com"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("invalid config had no errors")
+	}
+	for _, want := range []string{"server.tls_cert", "log.level", "admin.user", "state.encrypt", "webhooks.urls", "webhooks.secret", "db.driver", "server.base_url"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error didn't mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func TestExampleConfig(t *testing.T) {
+	cfg, err := Load("../srordle.example.toml")
+	if err != nil {
+		t.Fatalf("failed to load example config: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("example config was invalid: %v", err)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	path := writeConfig(t, `
+[words]
+dictionary_path = "dict.txt"
+
+[channels.work]
+target_words_path = "work-targets.txt"
+shape = ["xxxxxxx", "xxx.xxx"]
+full_attempts = 1
+
+[channels.family]
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	if diff := cmp.Diff([]string{"default", "family", "work"}, cfg.ChannelNames()); diff != "" {
+		t.Errorf("unexpected channel names (-want +got)\n%s", diff)
+	}
+
+	work, ok := cfg.Channel("work")
+	if !ok {
+		t.Fatal("work channel wasn't found")
+	}
+	if work.DictionaryPath != "dict.txt" || work.TargetWordsPath != "work-targets.txt" {
+		t.Errorf("unexpected work channel word lists %q, %q", work.DictionaryPath, work.TargetWordsPath)
+	}
+	if shape, err := work.GameShape(); err != nil || len(shape) != 2 {
+		t.Errorf("work channel shape = %v, %v, want two rows", shape, err)
+	}
+	if n := work.GameFullAttempts(); n != 1 {
+		t.Errorf("work channel full attempts = %d, want 1", n)
+	}
+
+	def, ok := cfg.Channel("default")
+	if !ok {
+		t.Fatal("default channel wasn't found")
+	}
+	if def.TargetWordsPath != cfg.Words.TargetWordsPath || def.GameFullAttempts() != 2 {
+		t.Errorf("unexpected default channel %+v", def)
+	}
+	if _, ok := cfg.Channel("missing"); ok {
+		t.Error("found a channel that wasn't configured")
+	}
+
+	cfg.Channels["Bad Name"] = Channel{}
+	cfg.Channels["family"] = Channel{Shape: []string{"xx"}}
+	err = cfg.Validate()
+	for _, want := range []string{"Bad Name", "channels.family.shape"} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("validation error didn't mention %s:\n%v", want, err)
+		}
+	}
+}
+
+func writeConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
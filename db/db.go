@@ -2,15 +2,22 @@ package db
 
 import (
 	"bytes"
-	"encoding/gob"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"log/slog"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/bcspragu/srordle/srordle"
 	"github.com/dgraph-io/badger/v3"
 )
 
+// DB is a Store backed by Badger. Badger locks its directory, so only one
+// process can have it open at a time.
 type DB struct {
 	db *badger.DB
 }
@@ -29,8 +36,46 @@ func ToDate(t time.Time) Date {
 	}
 }
 
-func gameKey(d Date) []byte {
-	return append([]byte("game:"), d.asBytes()...)
+// ParseDate parses a date in YYYY-MM-DD format.
+func ParseDate(in string) (Date, error) {
+	t, err := time.Parse("2006-01-02", in)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid date %q, should be YYYY-MM-DD: %w", in, err)
+	}
+	return ToDate(t), nil
+}
+
+// String returns the date in YYYY-MM-DD format.
+func (d Date) String() string {
+	return fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day)
+}
+
+func (d Date) LogValue() slog.Value {
+	return slog.StringValue(d.String())
+}
+
+// DefaultChannel is the channel used when none is given. Its games were
+// stored without a channel before channels existed, and those are moved into it
+// by a migration when the database is opened.
+const DefaultChannel = "default"
+
+var channelRE = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,31}$`)
+
+// ValidateChannel returns an error if the given channel name isn't a valid
+// slug, which is up to 32 lowercase letters, numbers, and dashes.
+func ValidateChannel(channel string) error {
+	if !channelRE.MatchString(channel) {
+		return fmt.Errorf("invalid channel %q, should be up to 32 lowercase letters, numbers, and dashes", channel)
+	}
+	return nil
+}
+
+func gameKey(channel string, d Date) []byte {
+	return append(gameKeyPrefix(channel), d.asBytes()...)
+}
+
+func gameKeyPrefix(channel string) []byte {
+	return []byte("channel:" + channel + ":")
 }
 
 func (d Date) AddDays(n int) Date {
@@ -38,40 +83,82 @@ func (d Date) AddDays(n int) Date {
 	return ToDate(t)
 }
 
+// After reports whether d is after other.
+func (d Date) After(other Date) bool {
+	if d.Year != other.Year {
+		return d.Year > other.Year
+	}
+	if d.Month != other.Month {
+		return d.Month > other.Month
+	}
+	return d.Day > other.Day
+}
+
+// asBytes returns the date as big-endian year, month, and day, so that keys
+// ending in dates sort by date.
 func (d Date) asBytes() []byte {
-	return []byte{
-		byte((d.Year << 24) & 0xFF),
-		byte((d.Year << 16) & 0xFF),
-		byte((d.Year << 8) & 0xFF),
-		byte((d.Year) & 0xFF),
-		byte(d.Month),
-		byte(d.Day),
+	b := binary.BigEndian.AppendUint32(nil, uint32(d.Year))
+	return append(b, byte(d.Month), byte(d.Day))
+}
+
+// dateFromBytes reverses asBytes.
+func dateFromBytes(b []byte) (Date, error) {
+	if len(b) != 6 {
+		return Date{}, fmt.Errorf("date key is %d bytes, expected 6", len(b))
 	}
+	return Date{
+		Year:  int32(binary.BigEndian.Uint32(b)),
+		Month: time.Month(b[4]),
+		Day:   int8(b[5]),
+	}, nil
 }
 
+// minDate and maxDate sort before and after every other date. Years before
+// the common era aren't supported, since their keys would sort after the rest.
+var (
+	minDate = Date{}
+	maxDate = Date{Year: math.MaxInt32, Month: time.December, Day: 31}
+)
+
 func Open(dir string) (*DB, error) {
-	db, err := badger.Open(badger.DefaultOptions(dir))
+	opts := badger.DefaultOptions(dir).WithLogger(badgerLogger{slog.Default().With("component", "badger")})
+	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &DB{db: db}, nil
+	d := &DB{db: db}
+	if err := d.migrateRequired(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return d, nil
 }
 
 func (d *DB) Close() error {
 	return d.db.Close()
 }
 
-func (d *DB) AddGame(date Date, game *srordle.Game) error {
-	var buf bytes.Buffer
-	if err := gob.NewEncoder(&buf).Encode(game); err != nil {
-		return fmt.Errorf("failed to gob encode game: %w", err)
+// IsOpen returns false once the database has been closed.
+func (d *DB) IsOpen() bool {
+	return !d.db.IsClosed()
+}
+
+// AddGame sets the game for the given channel and date, replacing any existing
+// game.
+func (d *DB) AddGame(channel string, date Date, game *srordle.Game) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	buf, err := encodeGame(game)
+	if err != nil {
+		return err
 	}
 
 	txn := d.db.NewTransaction(true) // Read-write txn
 	defer txn.Commit()               // Best effort commit on failure
 
-	if err := txn.SetEntry(badger.NewEntry(gameKey(date), buf.Bytes())); err != nil {
+	if err := txn.SetEntry(badger.NewEntry(gameKey(channel, date), buf)); err != nil {
 		return fmt.Errorf("failed to set entry in transaction: %w", err)
 	}
 
@@ -82,33 +169,147 @@ func (d *DB) AddGame(date Date, game *srordle.Game) error {
 	return nil
 }
 
-type errGameNotFound struct{}
+// ChangeGame sets c.New as the game for c's channel and date, replacing any
+// existing game, and records c, in one transaction.
+func (d *DB) ChangeGame(c *GameChange) error {
+	if err := ValidateChannel(c.Channel); err != nil {
+		return err
+	}
+	gameBuf, err := encodeGame(c.New)
+	if err != nil {
+		return err
+	}
+	changeBuf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(gameKey(c.Channel, c.Date), gameBuf)); err != nil {
+		return fmt.Errorf("failed to set game in transaction: %w", err)
+	}
+	if err := txn.SetEntry(badger.NewEntry(gameChangeKey(c), changeBuf)); err != nil {
+		return fmt.Errorf("failed to set game change in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// CreateGame sets the game for the given channel and date, returning
+// ErrGameExists if the date already has one.
+func (d *DB) CreateGame(channel string, date Date, game *srordle.Game) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	buf, err := encodeGame(game)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	_, err = getGameItem(txn, channel, date)
+	if err == nil {
+		return ErrGameExists
+	} else if !errors.Is(err, ErrGameNotFound) {
+		return fmt.Errorf("failed to check for existing game: %w", err)
+	}
+
+	if err := txn.SetEntry(badger.NewEntry(gameKey(channel, date), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
 
-func (errGameNotFound) Error() string {
-	return "game not found"
+	return nil
 }
 
-func (d *DB) Game(date Date) (*srordle.Game, error) {
-	txn := d.db.NewTransaction(false)
-	defer txn.Commit() // Best effort commit on failure
+// DeleteGame removes the game for the given channel and date, returning
+// ErrGameNotFound if there wasn't one.
+func (d *DB) DeleteGame(channel string, date Date) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	_, err := getGameItem(txn, channel, date)
+	if err != nil {
+		return err
+	}
+
+	if err := txn.Delete(gameKey(channel, date)); err != nil {
+		return fmt.Errorf("failed to delete entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
 
-	item, err := txn.Get(gameKey(date))
+// getGameItem returns the stored game for the given channel and date.
+func getGameItem(txn *badger.Txn, channel string, date Date) (*badger.Item, error) {
+	item, err := txn.Get(gameKey(channel, date))
 	if errors.Is(err, badger.ErrKeyNotFound) {
-		return nil, errGameNotFound{}
+		return nil, ErrGameNotFound
 	} else if err != nil {
-		return nil, fmt.Errorf("failed to load game bytes: %w", err)
+		return nil, fmt.Errorf("failed to load game: %w", err)
 	}
+	return item, nil
+}
 
+func decodeGameItem(item *badger.Item) (*srordle.Game, error) {
 	var g *srordle.Game
-	err = item.Value(func(val []byte) error {
-		if err := gob.NewDecoder(bytes.NewReader(val)).Decode(&g); err != nil {
-			return fmt.Errorf("failed to gob decode game: %w", err)
-		}
-		return nil
+	err := item.Value(func(val []byte) error {
+		var err error
+		g, _, err = decodeGame(val)
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to load item value: %w", err)
 	}
+	return g, nil
+}
+
+var (
+	// ErrGameNotFound is returned when there's no game scheduled for a date.
+	ErrGameNotFound = errors.New("game not found")
+	// ErrGameExists is returned when creating a game for a date that already
+	// has one.
+	ErrGameExists = errors.New("game already exists")
+)
+
+// Game returns the game for the given channel and date, or ErrGameNotFound if
+// there isn't one.
+func (d *DB) Game(channel string, date Date) (*srordle.Game, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	txn := d.db.NewTransaction(false)
+	defer txn.Commit() // Best effort commit on failure
+
+	item, err := getGameItem(txn, channel, date)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := decodeGameItem(item)
+	if err != nil {
+		return nil, err
+	}
 
 	if err := txn.Commit(); err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %w", err)
@@ -116,3 +317,530 @@ func (d *DB) Game(date Date) (*srordle.Game, error) {
 
 	return g, nil
 }
+
+// DatedGame is a game and the date it's scheduled for.
+type DatedGame struct {
+	Date Date
+	Game *srordle.Game
+}
+
+// Games returns the games in the channel scheduled from from to to, inclusive,
+// in date order. Dates without a game are skipped.
+func (d *DB) Games(channel string, from, to Date) ([]DatedGame, error) {
+	return collectGames(channel, from, to, d.EachGame)
+}
+
+// EachGame calls fn with each game in the channel scheduled from from to to,
+// inclusive, in date order, without loading them all at once. It stops at the
+// first error fn returns and returns it.
+func (d *DB) EachGame(channel string, from, to Date, fn func(DatedGame) error) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	prefix := gameKeyPrefix(channel)
+	end := gameKey(channel, to)
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+	defer it.Close()
+
+	for it.Seek(gameKey(channel, from)); it.ValidForPrefix(prefix); it.Next() {
+		item := it.Item()
+		if bytes.Compare(item.Key(), end) > 0 {
+			break
+		}
+		date, err := dateFromBytes(item.Key()[len(prefix):])
+		if err != nil {
+			return fmt.Errorf("invalid key %q: %w", item.Key(), err)
+		}
+		g, err := decodeGameItem(item)
+		if err != nil {
+			return fmt.Errorf("failed to load game for %s: %w", date, err)
+		}
+		if err := fn(DatedGame{Date: date, Game: g}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduledGames returns every game in the channel, past and future, in date
+// order.
+func (d *DB) ScheduledGames(channel string) ([]DatedGame, error) {
+	return d.Games(channel, minDate, maxDate)
+}
+
+// LastScheduledDate returns the date of the last game scheduled in the channel,
+// or ErrGameNotFound if it has none.
+func (d *DB) LastScheduledDate(channel string) (Date, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return Date{}, err
+	}
+
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	prefix := gameKeyPrefix(channel)
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, Reverse: true})
+	defer it.Close()
+
+	// In reverse, Seek finds the last key at or before the one given, which sorts
+	// after every key with the prefix.
+	it.Seek(append(prefix, 0xFF))
+	if !it.ValidForPrefix(prefix) {
+		return Date{}, ErrGameNotFound
+	}
+	key := it.Item().Key()
+	date, err := dateFromBytes(key[len(prefix):])
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid key %q: %w", key, err)
+	}
+	return date, nil
+}
+
+// Channels returns the name of every channel with at least one game, sorted.
+func (d *DB) Channels() ([]string, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	seen := make(map[string]bool)
+	prefix := []byte("channel:")
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix})
+	for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+		rest := it.Item().Key()[len(prefix):]
+		if i := bytes.IndexByte(rest, ':'); i > 0 {
+			seen[string(rest[:i])] = true
+		}
+	}
+	it.Close()
+
+	out := make([]string, 0, len(seen))
+	for ch := range seen {
+		out = append(out, ch)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Size returns the size in bytes of the database's LSM tree and value log.
+func (d *DB) Size() (lsm, vlog int64) {
+	return d.db.Size()
+}
+
+// RunGC runs garbage collection on the database's value log, rewriting files
+// where at least half the space can be reclaimed. It returns false if there
+// was nothing to rewrite.
+func (d *DB) RunGC() (bool, error) {
+	err := d.db.RunValueLogGC(0.5)
+	if errors.Is(err, badger.ErrNoRewrite) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to run value log GC: %w", err)
+	}
+	return true, nil
+}
+
+// badgerLogger sends Badger's logs to slog. Badger is chatty at the info
+// level, so those logs are demoted to debug.
+type badgerLogger struct {
+	l *slog.Logger
+}
+
+func (b badgerLogger) Errorf(format string, args ...interface{}) {
+	b.l.Error(badgerMsg(format, args))
+}
+
+func (b badgerLogger) Warningf(format string, args ...interface{}) {
+	b.l.Warn(badgerMsg(format, args))
+}
+
+func (b badgerLogger) Infof(format string, args ...interface{}) {
+	b.l.Debug(badgerMsg(format, args))
+}
+
+func (b badgerLogger) Debugf(format string, args ...interface{}) {
+	b.l.Debug(badgerMsg(format, args))
+}
+
+func badgerMsg(format string, args []interface{}) string {
+	return strings.TrimSpace(fmt.Sprintf(format, args...))
+}
+
+// GameChange is an audit record of a game being set by hand.
+type GameChange struct {
+	Channel string
+	Date    Date
+	// Old is the game that was replaced, or nil if there wasn't one.
+	Old *srordle.Game
+	New *srordle.Game
+	// Forced is true if the game had already started somewhere in the world
+	// when it was changed.
+	Forced    bool
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+// gameChangeKey sorts game changes by when they were made.
+func gameChangeKey(c *GameChange) []byte {
+	key := []byte("audit:")
+	key = binary.BigEndian.AppendUint64(key, uint64(c.ChangedAt.UnixNano()))
+	key = append(key, c.Channel...)
+	return append(key, c.Date.asBytes()...)
+}
+
+// AddGameChange records a change to a game.
+func (d *DB) AddGameChange(c *GameChange) error {
+	buf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(gameChangeKey(c), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GameChanges returns every recorded game change, oldest first.
+func (d *DB) GameChanges() ([]*GameChange, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	prefix := []byte("audit:")
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+	defer it.Close()
+
+	var out []*GameChange
+	for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+		var c *GameChange
+		err := it.Item().Value(func(val []byte) error {
+			var err error
+			c, _, err = decodeGameChange(val)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// DeadLetter is a webhook delivery that was given up on, kept so operators can
+// see what was missed and resend it by hand.
+type DeadLetter struct {
+	ID    string
+	URL   string
+	Event string
+	// Payload is the JSON body that would have been sent.
+	Payload   []byte
+	Attempts  int
+	LastError string
+	FailedAt  time.Time
+}
+
+// deadLetterKey sorts dead letters by when they failed.
+func deadLetterKey(dl *DeadLetter) []byte {
+	key := []byte("deadletter:")
+	key = binary.BigEndian.AppendUint64(key, uint64(dl.FailedAt.UnixNano()))
+	return append(key, dl.ID...)
+}
+
+// AddDeadLetter records a webhook delivery that failed permanently.
+func (d *DB) AddDeadLetter(dl *DeadLetter) error {
+	buf, err := encodeDeadLetter(dl)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(deadLetterKey(dl), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// DeadLetters returns every recorded dead letter, oldest first.
+func (d *DB) DeadLetters() ([]*DeadLetter, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	prefix := []byte("deadletter:")
+	it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+	defer it.Close()
+
+	var out []*DeadLetter
+	for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+		var dl *DeadLetter
+		err := it.Item().Value(func(val []byte) error {
+			var err error
+			dl, _, err = decodeDeadLetter(val)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dl)
+	}
+	return out, nil
+}
+
+// SharedResult is a finished game as shared by a player, with only the status
+// of each letter, so it doesn't give away the answer.
+type SharedResult struct {
+	Channel string
+	Date    Date
+	Won     bool
+	// Rows are the statuses of each letter of each guess, in order.
+	Rows      [][]srordle.LetterStatus
+	CreatedAt time.Time
+}
+
+// ErrShareNotFound is returned when there's no shared result with an ID.
+var ErrShareNotFound = errors.New("shared result not found")
+
+func shareKey(id string) []byte {
+	return []byte("share:" + id)
+}
+
+// AddShare stores a shared result under the given ID, replacing any existing
+// one.
+func (d *DB) AddShare(id string, res *SharedResult) error {
+	buf, err := encodeShare(res)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(shareKey(id), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Share returns the shared result with the given ID, or ErrShareNotFound if
+// there isn't one.
+func (d *DB) Share(id string) (*SharedResult, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	item, err := txn.Get(shareKey(id))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrShareNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load shared result: %w", err)
+	}
+
+	var res *SharedResult
+	err = item.Value(func(val []byte) error {
+		var err error
+		res, _, err = decodeShare(val)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load item value: %w", err)
+	}
+	return res, nil
+}
+
+// Session is a player's progress on a day's game, for clients that keep it in
+// the store instead of carrying it in a game state token.
+type Session struct {
+	ID        string
+	Channel   string
+	Date      Date
+	Progress  srordle.Progress
+	UpdatedAt time.Time
+}
+
+// ErrSessionNotFound is returned when there's no session with an ID.
+var ErrSessionNotFound = errors.New("session not found")
+
+func sessionKey(id string) []byte {
+	return []byte("session:" + id)
+}
+
+// AddSession stores the session under its ID, replacing any existing one.
+func (d *DB) AddSession(sess *Session) error {
+	buf, err := encodeSession(sess)
+	if err != nil {
+		return err
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(sessionKey(sess.ID), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Session returns the session with the given ID, or ErrSessionNotFound if
+// there isn't one.
+func (d *DB) Session(id string) (*Session, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	item, err := txn.Get(sessionKey(id))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	var sess *Session
+	err = item.Value(func(val []byte) error {
+		var err error
+		sess, _, err = decodeSession(val)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load item value: %w", err)
+	}
+	return sess, nil
+}
+
+// DeleteSession removes the session with the given ID, returning
+// ErrSessionNotFound if there wasn't one.
+func (d *DB) DeleteSession(id string) error {
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if _, err := txn.Get(sessionKey(id)); errors.Is(err, badger.ErrKeyNotFound) {
+		return ErrSessionNotFound
+	} else if err != nil {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+
+	if err := txn.Delete(sessionKey(id)); err != nil {
+		return fmt.Errorf("failed to delete entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Stats are how players did on a day's game in a channel.
+type Stats struct {
+	Channel string
+	Date    Date
+	// Played is how many players finished the game, and Won is how many of
+	// them won it.
+	Played int
+	Won    int
+	// WinGuesses counts wins by how many guesses they took.
+	WinGuesses map[int]int
+}
+
+func (s *Stats) record(won bool, guesses int) {
+	s.Played++
+	if !won {
+		return
+	}
+	s.Won++
+	if s.WinGuesses == nil {
+		s.WinGuesses = make(map[int]int)
+	}
+	s.WinGuesses[guesses]++
+}
+
+func statsKey(channel string, date Date) []byte {
+	return append([]byte("stats:"+channel+":"), date.asBytes()...)
+}
+
+// RecordResult adds a finished game to the stats for the channel and date.
+// Guesses is how many guesses the player made, and is only counted for wins.
+func (d *DB) RecordResult(channel string, date Date, won bool, guesses int) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	// Players finishing at the same time conflict with each other, so retry
+	// until this one doesn't.
+	for {
+		err := d.db.Update(func(txn *badger.Txn) error {
+			st, err := getStats(txn, channel, date)
+			if err != nil {
+				return err
+			}
+			st.record(won, guesses)
+			buf, err := encodeStats(st)
+			if err != nil {
+				return err
+			}
+			return txn.SetEntry(badger.NewEntry(statsKey(channel, date), buf))
+		})
+		if errors.Is(err, badger.ErrConflict) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to record result: %w", err)
+		}
+		return nil
+	}
+}
+
+// Stats returns the stats for the channel and date, which are empty if no
+// results have been recorded.
+func (d *DB) Stats(channel string, date Date) (*Stats, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return getStats(txn, channel, date)
+}
+
+func getStats(txn *badger.Txn, channel string, date Date) (*Stats, error) {
+	item, err := txn.Get(statsKey(channel, date))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return &Stats{Channel: channel, Date: date}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load stats: %w", err)
+	}
+
+	var st *Stats
+	err = item.Value(func(val []byte) error {
+		var err error
+		st, _, err = decodeStats(val)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load item value: %w", err)
+	}
+	return st, nil
+}
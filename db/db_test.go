@@ -0,0 +1,234 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChannels(t *testing.T) {
+	d := openTestDB(t)
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+
+	if err := d.AddGame(DefaultChannel, date, testGame("detract")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	if err := d.AddGame("work", date, testGame("cottage")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	assertWord(t, d, DefaultChannel, date, "detract")
+	assertWord(t, d, "work", date, "cottage")
+	if _, err := d.Game("family", date); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("Game in an empty channel returned %v, want %v", err, ErrGameNotFound)
+	}
+
+	if err := d.DeleteGame("work", date); err != nil {
+		t.Fatalf("DeleteGame: %v", err)
+	}
+	assertWord(t, d, DefaultChannel, date, "detract")
+
+	if err := d.AddGame("Not A Slug", date, testGame("detract")); err == nil {
+		t.Error("AddGame with an invalid channel didn't return an error")
+	}
+}
+
+func TestGames(t *testing.T) {
+	d := openTestDB(t)
+	start := Date{Year: 2022, Month: time.December, Day: 30}
+	for i, word := range []string{"detract", "cottage", "", "carrots"} {
+		if word == "" {
+			continue
+		}
+		if err := d.AddGame(DefaultChannel, start.AddDays(i), testGame(word)); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+	if err := d.AddGame("work", start, testGame("cottage")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	got, err := d.Games(DefaultChannel, start.AddDays(-1), start.AddDays(3))
+	if err != nil {
+		t.Fatalf("Games: %v", err)
+	}
+	var gotDates, gotWords []string
+	for _, dg := range got {
+		gotDates = append(gotDates, dg.Date.String())
+		gotWords = append(gotWords, dg.Game.TargetWord)
+	}
+	if diff := cmp.Diff([]string{"2022-12-30", "2022-12-31", "2023-01-02"}, gotDates); diff != "" {
+		t.Errorf("unexpected dates (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"detract", "cottage", "carrots"}, gotWords); diff != "" {
+		t.Errorf("unexpected words (-want +got)\n%s", diff)
+	}
+
+	if got, err := d.Games(DefaultChannel, start.AddDays(3), start); err != nil || len(got) != 0 {
+		t.Errorf("Games with from after to = %v, %v, want no games", got, err)
+	}
+}
+
+func TestScheduledGames(t *testing.T) {
+	d := openTestDB(t)
+	games := map[Date]string{
+		{Year: 2023, Month: time.January, Day: 2}:   "detract",
+		{Year: 2022, Month: time.August, Day: 20}:   "cottage",
+		{Year: 2022, Month: time.December, Day: 30}: "example",
+		{Year: 2022, Month: time.December, Day: 31}: "carrots",
+		// Keys used to only keep the low byte of the year.
+		{Year: 2278, Month: time.January, Day: 1}: "century",
+	}
+	for date, word := range games {
+		if err := d.AddGame(DefaultChannel, date, testGame(word)); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+	if err := d.AddGame("work", Date{Year: 2022, Month: time.December, Day: 31}, testGame("working")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	got, err := d.ScheduledGames(DefaultChannel)
+	if err != nil {
+		t.Fatalf("ScheduledGames: %v", err)
+	}
+	var gotGames []string
+	for _, dg := range got {
+		gotGames = append(gotGames, dg.Date.String()+" "+dg.Game.TargetWord)
+	}
+	want := []string{"2022-08-20 cottage", "2022-12-30 example", "2022-12-31 carrots", "2023-01-02 detract", "2278-01-01 century"}
+	if diff := cmp.Diff(want, gotGames); diff != "" {
+		t.Errorf("unexpected games (-want +got)\n%s", diff)
+	}
+
+	channels, err := d.Channels()
+	if err != nil {
+		t.Fatalf("Channels: %v", err)
+	}
+	if diff := cmp.Diff([]string{DefaultChannel, "work"}, channels); diff != "" {
+		t.Errorf("unexpected channels (-want +got)\n%s", diff)
+	}
+}
+
+func TestLastScheduledDate(t *testing.T) {
+	d := openTestDB(t)
+	if _, err := d.LastScheduledDate(DefaultChannel); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("LastScheduledDate with no games returned %v, want %v", err, ErrGameNotFound)
+	}
+
+	last := Date{Year: 2023, Month: time.January, Day: 2}
+	for _, date := range []Date{last, last.AddDays(-3), last.AddDays(-30)} {
+		if err := d.AddGame(DefaultChannel, date, testGame("detract")); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+	// Games in other channels, including ones whose names sort just after, are
+	// ignored.
+	for _, ch := range []string{"defaults", "work"} {
+		if err := d.AddGame(ch, last.AddDays(10), testGame("cottage")); err != nil {
+			t.Fatalf("AddGame: %v", err)
+		}
+	}
+
+	got, err := d.LastScheduledDate(DefaultChannel)
+	if err != nil {
+		t.Fatalf("LastScheduledDate: %v", err)
+	}
+	if got != last {
+		t.Errorf("LastScheduledDate = %s, want %s", got, last)
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	d := openTestDB(t)
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+
+	// Added out of order, but listed oldest first.
+	later := &DeadLetter{ID: "b", URL: "https://example.com/hook", Event: "game.live", Payload: []byte(`{}`), Attempts: 5, LastError: "got status 500", FailedAt: now.Add(time.Minute)}
+	earlier := &DeadLetter{ID: "a", URL: "https://example.com/hook", Event: "game.finished", Payload: []byte(`{}`), Attempts: 1, LastError: "got status 404", FailedAt: now}
+	for _, dl := range []*DeadLetter{later, earlier} {
+		if err := d.AddDeadLetter(dl); err != nil {
+			t.Fatalf("AddDeadLetter: %v", err)
+		}
+	}
+
+	got, err := d.DeadLetters()
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if diff := cmp.Diff([]*DeadLetter{earlier, later}, got); diff != "" {
+		t.Errorf("unexpected dead letters (-want +got)\n%s", diff)
+	}
+}
+
+func TestGameChanges(t *testing.T) {
+	d := openTestDB(t)
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	date := Date{Year: 2022, Month: time.August, Day: 21}
+
+	// Added out of order, but listed oldest first.
+	later := &GameChange{Channel: DefaultChannel, Date: date, Old: testGame("detract"), New: testGame("cottage"), Forced: true, ChangedBy: "srini", ChangedAt: now.Add(time.Minute)}
+	earlier := &GameChange{Channel: "work", Date: date, New: testGame("detract"), ChangedBy: "srini", ChangedAt: now}
+	for _, c := range []*GameChange{later, earlier} {
+		if err := d.AddGameChange(c); err != nil {
+			t.Fatalf("AddGameChange: %v", err)
+		}
+	}
+
+	got, err := d.GameChanges()
+	if err != nil {
+		t.Fatalf("GameChanges: %v", err)
+	}
+	if diff := cmp.Diff([]*GameChange{earlier, later}, got); diff != "" {
+		t.Errorf("unexpected game changes (-want +got)\n%s", diff)
+	}
+}
+
+func TestDecodeNewerVersion(t *testing.T) {
+	d := openTestDB(t)
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+
+	// A record written by a newer build, whose fields might have changed.
+	buf, err := encodeRecord(gameVersion+1, toStoredGame(testGame("detract")))
+	if err != nil {
+		t.Fatalf("encodeRecord: %v", err)
+	}
+	err = d.db.Update(func(txn *badger.Txn) error {
+		return txn.Set(gameKey(DefaultChannel, date), buf)
+	})
+	if err != nil {
+		t.Fatalf("failed to write record: %v", err)
+	}
+
+	if g, err := d.Game(DefaultChannel, date); err == nil {
+		t.Errorf("Game of a newer version returned %+v, want an error", g)
+	}
+}
+
+func testGame(word string) *srordle.Game {
+	return &srordle.Game{TargetWord: word, Shape: srordle.DefaultShape(), FullAttempts: 2}
+}
+
+func assertWord(t *testing.T, d Store, channel string, date Date, want string) {
+	t.Helper()
+	g, err := d.Game(channel, date)
+	if err != nil {
+		t.Fatalf("Game(%q, %s): %v", channel, date, err)
+	}
+	if g.TargetWord != want {
+		t.Errorf("Game(%q, %s) target word = %q, want %q", channel, date, g.TargetWord, want)
+	}
+}
+
+func openTestDB(t *testing.T) *DB {
+	d, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
@@ -0,0 +1,226 @@
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// Every value is stored as JSON in an envelope with the version of its schema,
+// so that its fields can change without breaking records written before. When
+// a type's fields change, bump its version below, teach its decode function to
+// read the old version, and add a migration (see migrate.go) to rewrite old
+// records.
+//
+// Before envelopes, values were stored as raw gob, which is treated as version
+// 0 and can still be read.
+const (
+	gameVersion       = 1
+	gameChangeVersion = 1
+	deadLetterVersion = 1
+	shareVersion      = 1
+	sessionVersion    = 1
+	statsVersion      = 1
+)
+
+type envelope struct {
+	Version int
+	Data    json.RawMessage
+}
+
+func encodeRecord(version int, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON encode record: %w", err)
+	}
+	buf, err := json.Marshal(envelope{Version: version, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to JSON encode envelope: %w", err)
+	}
+	return buf, nil
+}
+
+// decodeRecord decodes a value written by encodeRecord into v, returning its
+// version. If the value was written as gob before envelopes, it's decoded into
+// legacy instead and the version is 0. Values newer than current, the version
+// this build writes, were written by a newer build and return an error, since
+// their fields may not mean what this one thinks.
+func decodeRecord(val []byte, current int, v, legacy any) (int, error) {
+	var env envelope
+	if err := json.Unmarshal(val, &env); err == nil && env.Version > 0 {
+		if env.Version > current {
+			return 0, fmt.Errorf("unsupported version %d, expected 1 to %d", env.Version, current)
+		}
+		if err := json.Unmarshal(env.Data, v); err != nil {
+			return 0, fmt.Errorf("failed to JSON decode version %d record: %w", env.Version, err)
+		}
+		return env.Version, nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(val)).Decode(legacy); err != nil {
+		return 0, fmt.Errorf("failed to gob decode record: %w", err)
+	}
+	return 0, nil
+}
+
+// storedGame is how a srordle.Game is stored, kept separate so that changes to
+// srordle.Game don't change the stored format by accident.
+type storedGame struct {
+	TargetWord string
+	// Shape is in the format srordle.ParseShape reads, e.g. "xxx.xxx".
+	Shape        []string
+	FullAttempts int
+}
+
+func toStoredGame(g *srordle.Game) *storedGame {
+	if g == nil {
+		return nil
+	}
+	return &storedGame{
+		TargetWord:   g.TargetWord,
+		Shape:        g.Shape.Strings(),
+		FullAttempts: g.FullAttempts,
+	}
+}
+
+func (sg *storedGame) toGame() *srordle.Game {
+	if sg == nil {
+		return nil
+	}
+	// This doesn't use srordle.ParseShape, which would reject games that were
+	// stored without being validated.
+	var shape srordle.Shape
+	for _, rowStr := range sg.Shape {
+		row := make(srordle.Row, 0, len(rowStr))
+		for _, c := range rowStr {
+			row = append(row, c == 'x')
+		}
+		shape = append(shape, row)
+	}
+	return &srordle.Game{
+		TargetWord:   sg.TargetWord,
+		Shape:        shape,
+		FullAttempts: sg.FullAttempts,
+	}
+}
+
+func encodeGame(game *srordle.Game) ([]byte, error) {
+	return encodeRecord(gameVersion, toStoredGame(game))
+}
+
+func decodeGame(val []byte) (*srordle.Game, int, error) {
+	var (
+		sg     *storedGame
+		legacy *srordle.Game
+	)
+	version, err := decodeRecord(val, gameVersion, &sg, &legacy)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode game: %w", err)
+	}
+	if version == 0 {
+		return legacy, 0, nil
+	}
+	return sg.toGame(), version, nil
+}
+
+// storedGameChange is how a GameChange is stored, with its games stored like
+// any other game.
+type storedGameChange struct {
+	Channel   string
+	Date      Date
+	Old       *storedGame
+	New       *storedGame
+	Forced    bool
+	ChangedBy string
+	ChangedAt time.Time
+}
+
+func encodeGameChange(c *GameChange) ([]byte, error) {
+	return encodeRecord(gameChangeVersion, &storedGameChange{
+		Channel:   c.Channel,
+		Date:      c.Date,
+		Old:       toStoredGame(c.Old),
+		New:       toStoredGame(c.New),
+		Forced:    c.Forced,
+		ChangedBy: c.ChangedBy,
+		ChangedAt: c.ChangedAt,
+	})
+}
+
+func decodeGameChange(val []byte) (*GameChange, int, error) {
+	var (
+		sc     *storedGameChange
+		legacy *GameChange
+	)
+	version, err := decodeRecord(val, gameChangeVersion, &sc, &legacy)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode game change: %w", err)
+	}
+	if version == 0 {
+		return legacy, 0, nil
+	}
+	return &GameChange{
+		Channel:   sc.Channel,
+		Date:      sc.Date,
+		Old:       sc.Old.toGame(),
+		New:       sc.New.toGame(),
+		Forced:    sc.Forced,
+		ChangedBy: sc.ChangedBy,
+		ChangedAt: sc.ChangedAt,
+	}, version, nil
+}
+
+func encodeDeadLetter(dl *DeadLetter) ([]byte, error) {
+	return encodeRecord(deadLetterVersion, dl)
+}
+
+func decodeDeadLetter(val []byte) (*DeadLetter, int, error) {
+	var dl *DeadLetter
+	version, err := decodeRecord(val, deadLetterVersion, &dl, &dl)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode dead letter: %w", err)
+	}
+	return dl, version, nil
+}
+
+func encodeShare(res *SharedResult) ([]byte, error) {
+	return encodeRecord(shareVersion, res)
+}
+
+func decodeShare(val []byte) (*SharedResult, int, error) {
+	var res *SharedResult
+	version, err := decodeRecord(val, shareVersion, &res, &res)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode shared result: %w", err)
+	}
+	return res, version, nil
+}
+
+func encodeSession(sess *Session) ([]byte, error) {
+	return encodeRecord(sessionVersion, sess)
+}
+
+func decodeSession(val []byte) (*Session, int, error) {
+	var sess *Session
+	version, err := decodeRecord(val, sessionVersion, &sess, &sess)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return sess, version, nil
+}
+
+func encodeStats(st *Stats) ([]byte, error) {
+	return encodeRecord(statsVersion, st)
+}
+
+func decodeStats(val []byte) (*Stats, int, error) {
+	var st *Stats
+	version, err := decodeRecord(val, statsVersion, &st, &st)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode stats: %w", err)
+	}
+	return st, version, nil
+}
@@ -0,0 +1,441 @@
+package db
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// errClosed is returned when using a Memory store after it's closed.
+var errClosed = errors.New("store is closed")
+
+// Memory is a Store that keeps everything in memory, for tests. Values are
+// stored encoded like the other stores, so callers can't change stored
+// records through pointers they passed in or got back.
+type Memory struct {
+	mu     sync.Mutex
+	closed bool
+	// games are keyed by channel, then date.
+	games map[string]map[Date][]byte
+	// Game changes and dead letters are keyed like in DB, which sorts them.
+	changes     map[string][]byte
+	deadLetters map[string][]byte
+	shares      map[string][]byte
+	sessions    map[string][]byte
+	// stats are keyed like in DB.
+	stats map[string][]byte
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{
+		games:       make(map[string]map[Date][]byte),
+		changes:     make(map[string][]byte),
+		deadLetters: make(map[string][]byte),
+		shares:      make(map[string][]byte),
+		sessions:    make(map[string][]byte),
+		stats:       make(map[string][]byte),
+	}
+}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *Memory) IsOpen() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return !m.closed
+}
+
+// Migrate does nothing, since there's never any old data in memory.
+func (m *Memory) Migrate() ([]MigrationResult, error) {
+	return nil, nil
+}
+
+// lock locks the store, returning errClosed if it's been closed. The caller
+// must unlock it if lock doesn't return an error.
+func (m *Memory) lock() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return errClosed
+	}
+	return nil
+}
+
+func (m *Memory) AddGame(channel string, date Date, game *srordle.Game) error {
+	return m.setGame(channel, date, game, true)
+}
+
+func (m *Memory) ChangeGame(c *GameChange) error {
+	if err := ValidateChannel(c.Channel); err != nil {
+		return err
+	}
+	gameBuf, err := encodeGame(c.New)
+	if err != nil {
+		return err
+	}
+	changeBuf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	games, ok := m.games[c.Channel]
+	if !ok {
+		games = make(map[Date][]byte)
+		m.games[c.Channel] = games
+	}
+	games[c.Date] = gameBuf
+	m.changes[string(gameChangeKey(c))] = changeBuf
+	return nil
+}
+
+func (m *Memory) CreateGame(channel string, date Date, game *srordle.Game) error {
+	return m.setGame(channel, date, game, false)
+}
+
+func (m *Memory) setGame(channel string, date Date, game *srordle.Game, replace bool) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	buf, err := encodeGame(game)
+	if err != nil {
+		return err
+	}
+
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	games, ok := m.games[channel]
+	if !ok {
+		games = make(map[Date][]byte)
+		m.games[channel] = games
+	}
+	if _, ok := games[date]; ok && !replace {
+		return ErrGameExists
+	}
+	games[date] = buf
+	return nil
+}
+
+func (m *Memory) DeleteGame(channel string, date Date) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	games := m.games[channel]
+	if _, ok := games[date]; !ok {
+		return ErrGameNotFound
+	}
+	delete(games, date)
+	if len(games) == 0 {
+		delete(m.games, channel)
+	}
+	return nil
+}
+
+func (m *Memory) Game(channel string, date Date) (*srordle.Game, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	buf, ok := m.games[channel][date]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	g, _, err := decodeGame(buf)
+	return g, err
+}
+
+func (m *Memory) Games(channel string, from, to Date) ([]DatedGame, error) {
+	return collectGames(channel, from, to, m.EachGame)
+}
+
+// EachGame doesn't hold the lock while calling fn, so fn can use the store.
+func (m *Memory) EachGame(channel string, from, to Date, fn func(DatedGame) error) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	type stored struct {
+		date Date
+		buf  []byte
+	}
+	var games []stored
+	for date, buf := range m.games[channel] {
+		if !from.After(date) && !date.After(to) {
+			games = append(games, stored{date: date, buf: buf})
+		}
+	}
+	m.mu.Unlock()
+
+	sort.Slice(games, func(i, j int) bool { return games[j].date.After(games[i].date) })
+	for _, sg := range games {
+		g, _, err := decodeGame(sg.buf)
+		if err != nil {
+			return err
+		}
+		if err := fn(DatedGame{Date: sg.date, Game: g}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ScheduledGames(channel string) ([]DatedGame, error) {
+	return m.Games(channel, minDate, maxDate)
+}
+
+func (m *Memory) LastScheduledDate(channel string) (Date, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return Date{}, err
+	}
+	if err := m.lock(); err != nil {
+		return Date{}, err
+	}
+	defer m.mu.Unlock()
+
+	var (
+		last  Date
+		found bool
+	)
+	for date := range m.games[channel] {
+		if !found || date.After(last) {
+			last, found = date, true
+		}
+	}
+	if !found {
+		return Date{}, ErrGameNotFound
+	}
+	return last, nil
+}
+
+func (m *Memory) Channels() ([]string, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	out := make([]string, 0, len(m.games))
+	for ch := range m.games {
+		out = append(out, ch)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (m *Memory) AddGameChange(c *GameChange) error {
+	buf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.changes[string(gameChangeKey(c))] = buf
+	return nil
+}
+
+func (m *Memory) GameChanges() ([]*GameChange, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	vals := sortedValues(m.changes)
+	m.mu.Unlock()
+
+	var out []*GameChange
+	for _, val := range vals {
+		c, _, err := decodeGameChange(val)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+func (m *Memory) AddDeadLetter(dl *DeadLetter) error {
+	buf, err := encodeDeadLetter(dl)
+	if err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.deadLetters[string(deadLetterKey(dl))] = buf
+	return nil
+}
+
+func (m *Memory) DeadLetters() ([]*DeadLetter, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	vals := sortedValues(m.deadLetters)
+	m.mu.Unlock()
+
+	var out []*DeadLetter
+	for _, val := range vals {
+		dl, _, err := decodeDeadLetter(val)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dl)
+	}
+	return out, nil
+}
+
+func (m *Memory) AddShare(id string, res *SharedResult) error {
+	buf, err := encodeShare(res)
+	if err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.shares[id] = buf
+	return nil
+}
+
+func (m *Memory) Share(id string) (*SharedResult, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	buf, ok := m.shares[id]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrShareNotFound
+	}
+	res, _, err := decodeShare(buf)
+	return res, err
+}
+
+func (m *Memory) AddSession(sess *Session) error {
+	buf, err := encodeSession(sess)
+	if err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	m.sessions[sess.ID] = buf
+	return nil
+}
+
+func (m *Memory) Session(id string) (*Session, error) {
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	buf, ok := m.sessions[id]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	sess, _, err := decodeSession(buf)
+	return sess, err
+}
+
+func (m *Memory) DeleteSession(id string) error {
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	if _, ok := m.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(m.sessions, id)
+	return nil
+}
+
+func (m *Memory) RecordResult(channel string, date Date, won bool, guesses int) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	if err := m.lock(); err != nil {
+		return err
+	}
+	defer m.mu.Unlock()
+
+	key := string(statsKey(channel, date))
+	st, err := m.statsLocked(key, channel, date)
+	if err != nil {
+		return err
+	}
+	st.record(won, guesses)
+	buf, err := encodeStats(st)
+	if err != nil {
+		return err
+	}
+	m.stats[key] = buf
+	return nil
+}
+
+func (m *Memory) Stats(channel string, date Date) (*Stats, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+	if err := m.lock(); err != nil {
+		return nil, err
+	}
+	defer m.mu.Unlock()
+
+	return m.statsLocked(string(statsKey(channel, date)), channel, date)
+}
+
+// statsLocked returns the stats stored under key, which must be the key for
+// the channel and date. The caller must hold the lock.
+func (m *Memory) statsLocked(key, channel string, date Date) (*Stats, error) {
+	buf, ok := m.stats[key]
+	if !ok {
+		return &Stats{Channel: channel, Date: date}, nil
+	}
+	st, _, err := decodeStats(buf)
+	return st, err
+}
+
+// sortedValues returns the values in the map, sorted by their keys.
+func sortedValues(m map[string][]byte) [][]byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([][]byte, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, m[k])
+	}
+	return out
+}
@@ -0,0 +1,322 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// migration upgrades data written by an older version of the database. Every
+// migration must be safe to run more than once, in case it fails partway
+// through and is run again.
+type migration struct {
+	// name identifies the migration once it has been applied, so it must never
+	// change.
+	name string
+	// run applies the migration, returning how many records it changed.
+	run func(d *DB) (int, error)
+	// required migrations are run when the database is opened, because the
+	// data they upgrade can't be read until they have been. They can't depend
+	// on earlier migrations that aren't required.
+	required bool
+}
+
+// migrations are run in order by Migrate. New ones go at the end.
+var migrations = []migration{
+	{name: "0001-versioned-records", run: (*DB).versionRecords},
+	{name: "0002-sortable-date-keys", run: (*DB).sortableDateKeys, required: true},
+}
+
+// MigrationResult is what happened to a migration when running Migrate.
+type MigrationResult struct {
+	Name string
+	// AlreadyApplied is true if the migration was skipped because it was applied
+	// by an earlier call to Migrate.
+	AlreadyApplied bool
+	// Changed is how many records were rewritten.
+	Changed int
+}
+
+// appliedMigration is stored for each migration once it has been applied.
+type appliedMigration struct {
+	AppliedAt time.Time
+	Changed   int
+}
+
+func migrationKey(name string) []byte {
+	return []byte("migration:" + name)
+}
+
+// Migrate applies every migration that hasn't been applied to the database yet,
+// in order. It's safe to call repeatedly, and stops at the first migration that
+// fails.
+func (d *DB) Migrate() ([]MigrationResult, error) {
+	return d.runMigrations(false)
+}
+
+// migrateRequired applies the required migrations that haven't been applied
+// yet, logging the ones it applies.
+func (d *DB) migrateRequired() error {
+	results, err := d.runMigrations(true)
+	for _, res := range results {
+		if !res.AlreadyApplied {
+			slog.Info("applied database migration", "migration", res.Name, "changed", res.Changed)
+		}
+	}
+	return err
+}
+
+func (d *DB) runMigrations(requiredOnly bool) ([]MigrationResult, error) {
+	var out []MigrationResult
+	for _, m := range migrations {
+		if requiredOnly && !m.required {
+			continue
+		}
+		applied, err := d.migrationApplied(m.name)
+		if err != nil {
+			return out, err
+		}
+		if applied {
+			out = append(out, MigrationResult{Name: m.name, AlreadyApplied: true})
+			continue
+		}
+
+		n, err := m.run(d)
+		if err != nil {
+			return out, fmt.Errorf("failed to run migration %q: %w", m.name, err)
+		}
+		if err := d.markMigrationApplied(m.name, n); err != nil {
+			return out, err
+		}
+		out = append(out, MigrationResult{Name: m.name, Changed: n})
+	}
+	return out, nil
+}
+
+func (d *DB) migrationApplied(name string) (bool, error) {
+	txn := d.db.NewTransaction(false)
+	defer txn.Discard()
+
+	_, err := txn.Get(migrationKey(name))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("failed to check migration %q: %w", name, err)
+	}
+	return true, nil
+}
+
+func (d *DB) markMigrationApplied(name string, changed int) error {
+	buf, err := json.Marshal(appliedMigration{AppliedAt: time.Now(), Changed: changed})
+	if err != nil {
+		return fmt.Errorf("failed to JSON encode migration: %w", err)
+	}
+
+	txn := d.db.NewTransaction(true) // Read-write txn
+	defer txn.Discard()
+
+	if err := txn.SetEntry(badger.NewEntry(migrationKey(name), buf)); err != nil {
+		return fmt.Errorf("failed to set entry in transaction: %w", err)
+	}
+
+	if err := txn.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// rewriteValues calls fn with the value of every key with the given prefix, and
+// replaces the value with what fn returns, unless it returns nil. It returns
+// how many values were replaced. Writes are batched rather than done in one
+// transaction, which Badger limits in size.
+func (d *DB) rewriteValues(prefix []byte, fn func(val []byte) ([]byte, error)) (int, error) {
+	wb := d.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	n := 0
+	err := d.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+		defer it.Close()
+
+		for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return fmt.Errorf("failed to load value of %q: %w", item.Key(), err)
+			}
+			newVal, err := fn(val)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite %q: %w", item.Key(), err)
+			}
+			if newVal == nil {
+				continue
+			}
+			if err := wb.Set(item.KeyCopy(nil), newVal); err != nil {
+				return fmt.Errorf("failed to write %q: %w", item.Key(), err)
+			}
+			n++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if err := wb.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush writes: %w", err)
+	}
+	return n, nil
+}
+
+// versionRecords rewrites every record that's stored as raw gob in a versioned
+// envelope.
+func (d *DB) versionRecords() (int, error) {
+	games := reencode(decodeGame, encodeGame, gameVersion)
+	rewrites := []struct {
+		prefix []byte
+		fn     func([]byte) ([]byte, error)
+	}{
+		{[]byte("channel:"), games},
+		{[]byte("audit:"), reencode(decodeGameChange, encodeGameChange, gameChangeVersion)},
+		{[]byte("deadletter:"), reencode(decodeDeadLetter, encodeDeadLetter, deadLetterVersion)},
+		{[]byte("share:"), reencode(decodeShare, encodeShare, shareVersion)},
+	}
+
+	total := 0
+	for _, rw := range rewrites {
+		n, err := d.rewriteValues(rw.prefix, rw.fn)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// reencode returns a function for rewriteValues that decodes values and encodes
+// them again if they're older than the current version.
+func reencode[T any](decode func([]byte) (T, int, error), encode func(T) ([]byte, error), current int) func([]byte) ([]byte, error) {
+	return func(val []byte) ([]byte, error) {
+		v, version, err := decode(val)
+		if err != nil {
+			return nil, err
+		}
+		if version >= current {
+			return nil, nil
+		}
+		return encode(v)
+	}
+}
+
+// Before this migration, asBytes shifted the year left instead of right, so
+// every date key had three zero bytes followed by the low byte of the year,
+// and keys didn't sort by date. Games in the default channel were also stored
+// under "game:" before channels existed.
+var legacyGameKeyPrefix = []byte("game:")
+
+// isUnsortableDate returns true if b is a date written by the old asBytes.
+// Current dates only start with three zero bytes for years before 256.
+func isUnsortableDate(b []byte) bool {
+	return len(b) == 6 && b[0] == 0 && b[1] == 0 && b[2] == 0
+}
+
+// unsortableDateFromBytes reads a date written by the old asBytes. Since it
+// only kept the low byte of the year, the year is taken to be the one closest
+// to the current year with that low byte.
+func unsortableDateFromBytes(b []byte, now time.Time) Date {
+	cur := int32(now.Year())
+	year := cur&^0xFF | int32(b[3])
+	switch {
+	case year-cur > 128:
+		year -= 256
+	case cur-year > 128:
+		year += 256
+	}
+	return Date{Year: year, Month: time.Month(b[4]), Day: int8(b[5])}
+}
+
+// sortableDateKeys moves games stored under old date keys, including legacy
+// default channel games, to keys that sort by date. If a game is already
+// stored under the new key, the old one is dropped, like channel games took
+// precedence over legacy ones when they were read.
+func (d *DB) sortableDateKeys() (int, error) {
+	type move struct {
+		from, to, val []byte
+	}
+	var (
+		moves []move
+		taken = make(map[string]bool)
+		now   = time.Now()
+	)
+	err := d.db.View(func(txn *badger.Txn) error {
+		// Channel games come first, so they take precedence over legacy ones for
+		// the same date.
+		for _, prefix := range [][]byte{[]byte("channel:"), legacyGameKeyPrefix} {
+			it := txn.NewIterator(badger.IteratorOptions{Prefix: prefix, PrefetchValues: true, PrefetchSize: 100})
+			for it.Rewind(); it.ValidForPrefix(prefix); it.Next() {
+				item := it.Item()
+				key := item.KeyCopy(nil)
+
+				chPrefix := gameKeyPrefix(DefaultChannel)
+				if bytes.Equal(prefix, legacyGameKeyPrefix) {
+					if len(key)-len(prefix) != 6 {
+						it.Close()
+						return fmt.Errorf("invalid key %q", key)
+					}
+				} else {
+					i := bytes.IndexByte(key[len(prefix):], ':')
+					if i < 0 {
+						it.Close()
+						return fmt.Errorf("invalid key %q", key)
+					}
+					chPrefix = key[:len(prefix)+i+1]
+					if !isUnsortableDate(key[len(chPrefix):]) {
+						taken[string(key)] = true
+						continue
+					}
+				}
+
+				val, err := item.ValueCopy(nil)
+				if err != nil {
+					it.Close()
+					return fmt.Errorf("failed to load value of %q: %w", key, err)
+				}
+				date := unsortableDateFromBytes(key[len(key)-6:], now)
+				to := append(append([]byte{}, chPrefix...), date.asBytes()...)
+				moves = append(moves, move{from: key, to: to, val: val})
+			}
+			it.Close()
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	wb := d.db.NewWriteBatch()
+	defer wb.Cancel()
+
+	n := 0
+	for _, m := range moves {
+		if !taken[string(m.to)] {
+			if err := wb.Set(m.to, m.val); err != nil {
+				return 0, fmt.Errorf("failed to write %q: %w", m.to, err)
+			}
+			taken[string(m.to)] = true
+			n++
+		}
+		if err := wb.Delete(m.from); err != nil {
+			return 0, fmt.Errorf("failed to delete %q: %w", m.from, err)
+		}
+	}
+	if err := wb.Flush(); err != nil {
+		return 0, fmt.Errorf("failed to flush writes: %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,205 @@
+package db
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMigrate(t *testing.T) {
+	d := openTestDB(t)
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+
+	// Write records the way they were stored before they were versioned.
+	dl := &DeadLetter{ID: "a", URL: "https://example.com/hook", Event: "game.live", Payload: []byte(`{}`), Attempts: 1, FailedAt: now}
+	share := &SharedResult{Channel: DefaultChannel, Date: date, Won: true, Rows: [][]srordle.LetterStatus{{srordle.Correct}}, CreatedAt: now}
+	change := &GameChange{Channel: "work", Date: date, New: testGame("cottage"), ChangedBy: "srini", ChangedAt: now}
+	legacy := map[string]any{
+		string(gameKey("work", date)):        testGame("cottage"),
+		string(deadLetterKey(dl)):            dl,
+		string(shareKey("0123456789abcdef")): share,
+		string(gameChangeKey(change)):        change,
+	}
+	err := d.db.Update(func(txn *badger.Txn) error {
+		for key, v := range legacy {
+			var buf bytes.Buffer
+			if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+				return err
+			}
+			if err := txn.Set([]byte(key), buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to write legacy records: %v", err)
+	}
+	// Records written since are already versioned, and are left alone.
+	if err := d.AddGame("work", date.AddDays(1), testGame("carrots")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	got, err := d.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	// Keys were made sortable when the database was opened.
+	want := []MigrationResult{
+		{Name: "0001-versioned-records", Changed: len(legacy)},
+		{Name: "0002-sortable-date-keys", AlreadyApplied: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected migration results (-want +got)\n%s", diff)
+	}
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		for key := range legacy {
+			item, err := txn.Get([]byte(key))
+			if err != nil {
+				return err
+			}
+			val, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			if !bytes.HasPrefix(val, []byte(`{"Version":1,`)) {
+				t.Errorf("%q wasn't rewritten in an envelope: %q", key, val)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read migrated records: %v", err)
+	}
+
+	assertWord(t, d, "work", date, "cottage")
+	assertWord(t, d, "work", date.AddDays(1), "carrots")
+	dls, err := d.DeadLetters()
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if diff := cmp.Diff([]*DeadLetter{dl}, dls); diff != "" {
+		t.Errorf("unexpected dead letters (-want +got)\n%s", diff)
+	}
+	gotShare, err := d.Share("0123456789abcdef")
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+	if diff := cmp.Diff(share, gotShare); diff != "" {
+		t.Errorf("unexpected shared result (-want +got)\n%s", diff)
+	}
+	changes, err := d.GameChanges()
+	if err != nil {
+		t.Fatalf("GameChanges: %v", err)
+	}
+	if diff := cmp.Diff([]*GameChange{change}, changes); diff != "" {
+		t.Errorf("unexpected game changes (-want +got)\n%s", diff)
+	}
+
+	// Running it again does nothing.
+	got, err = d.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	want = []MigrationResult{
+		{Name: "0001-versioned-records", AlreadyApplied: true},
+		{Name: "0002-sortable-date-keys", AlreadyApplied: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected migration results on second run (-want +got)\n%s", diff)
+	}
+}
+
+func TestSortableDateKeys(t *testing.T) {
+	dir := t.TempDir()
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+
+	// Write games under the keys used before dates were sortable, which only
+	// kept the low byte of the year, and under the key used before channels.
+	unsortable := func(prefix []byte, d Date) []byte {
+		return append(append([]byte{}, prefix...), 0, 0, 0, byte(d.Year), byte(d.Month), byte(d.Day))
+	}
+	old := map[string]string{
+		string(unsortable(gameKeyPrefix("work"), date)):            "working",
+		string(unsortable(gameKeyPrefix(DefaultChannel), date)):    "detract",
+		string(unsortable(legacyGameKeyPrefix, date)):              "ignored",
+		string(unsortable(legacyGameKeyPrefix, date.AddDays(200))): "carrots",
+	}
+	err = d.db.Update(func(txn *badger.Txn) error {
+		for key, word := range old {
+			buf, err := encodeGame(testGame(word))
+			if err != nil {
+				return err
+			}
+			if err := txn.Set([]byte(key), buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to write old games: %v", err)
+	}
+	if err := d.AddGame("work", date.AddDays(1), testGame("cottage")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+
+	n, err := d.sortableDateKeys()
+	if err != nil {
+		t.Fatalf("sortableDateKeys: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("sortableDateKeys moved %d games, want 3", n)
+	}
+	d.Close()
+
+	// The migration is safe to run again when the database is opened.
+	d, err = Open(dir)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+
+	gotGames := func(channel string) []string {
+		games, err := d.ScheduledGames(channel)
+		if err != nil {
+			t.Fatalf("ScheduledGames: %v", err)
+		}
+		var out []string
+		for _, dg := range games {
+			out = append(out, dg.Date.String()+" "+dg.Game.TargetWord)
+		}
+		return out
+	}
+	// Channel games take precedence over legacy ones for the same date.
+	if diff := cmp.Diff([]string{"2022-08-20 detract", "2023-03-08 carrots"}, gotGames(DefaultChannel)); diff != "" {
+		t.Errorf("unexpected default games (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"2022-08-20 working", "2022-08-21 cottage"}, gotGames("work")); diff != "" {
+		t.Errorf("unexpected work games (-want +got)\n%s", diff)
+	}
+
+	err = d.db.View(func(txn *badger.Txn) error {
+		for key := range old {
+			if _, err := txn.Get([]byte(key)); !errors.Is(err, badger.ErrKeyNotFound) {
+				t.Errorf("old key %q wasn't deleted, err = %v", key, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to read old keys: %v", err)
+	}
+}
@@ -0,0 +1,562 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+
+	// Registers the pure Go "sqlite" driver, so no cgo is needed.
+	_ "modernc.org/sqlite"
+)
+
+// SQLite is a Store backed by a SQLite database file. Unlike DB, more than one
+// process can use it at once, so the CLI can change games while the server is
+// running. Values are stored in the same versioned envelopes as DB.
+type SQLite struct {
+	db     *sql.DB
+	closed atomic.Bool
+}
+
+// sqliteBusyTimeout is how long a write waits for another process's write to
+// finish before failing.
+const sqliteBusyTimeout = 5 * time.Second
+
+// sqliteMigrations create and change the schema. They're all applied when the
+// database is opened, and each runs in a transaction, so unlike migrations for
+// DB they don't need to be safe to run more than once. New ones go at the end.
+var sqliteMigrations = []struct {
+	name string
+	stmt string
+}{
+	{
+		name: "0001-create-tables",
+		stmt: `
+CREATE TABLE games (
+	channel TEXT NOT NULL,
+	-- The date as YYYYMMDD, so it sorts by date.
+	date INTEGER NOT NULL,
+	game BLOB NOT NULL,
+	PRIMARY KEY (channel, date)
+);
+-- Game changes and dead letters are keyed like they are in Badger, which sorts
+-- them by when they happened.
+CREATE TABLE game_changes (
+	key BLOB PRIMARY KEY,
+	change BLOB NOT NULL
+);
+CREATE TABLE dead_letters (
+	key BLOB PRIMARY KEY,
+	dead_letter BLOB NOT NULL
+);
+CREATE TABLE shares (
+	id TEXT PRIMARY KEY,
+	result BLOB NOT NULL
+);`,
+	},
+	{
+		name: "0002-create-sessions-and-stats",
+		stmt: `
+CREATE TABLE sessions (
+	id TEXT PRIMARY KEY,
+	session BLOB NOT NULL
+);
+-- Stats are stored as counts instead of encoded values, so recording a result
+-- is a single upsert that doesn't race with other processes.
+CREATE TABLE stats (
+	channel TEXT NOT NULL,
+	date INTEGER NOT NULL,
+	played INTEGER NOT NULL,
+	won INTEGER NOT NULL,
+	PRIMARY KEY (channel, date)
+);
+CREATE TABLE stats_win_guesses (
+	channel TEXT NOT NULL,
+	date INTEGER NOT NULL,
+	guesses INTEGER NOT NULL,
+	wins INTEGER NOT NULL,
+	PRIMARY KEY (channel, date, guesses)
+);`,
+	},
+}
+
+// OpenSQLite opens the SQLite database at path, creating it if it doesn't
+// exist, and applies any migrations it's missing.
+func OpenSQLite(path string) (*SQLite, error) {
+	// WAL lets the server keep reading while another process writes.
+	q := url.Values{"_pragma": {
+		fmt.Sprintf("busy_timeout(%d)", sqliteBusyTimeout.Milliseconds()),
+		"journal_mode(WAL)",
+	}}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	s := &SQLite{db: db}
+	results, err := s.Migrate()
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	for _, res := range results {
+		if !res.AlreadyApplied {
+			slog.Info("applied database migration", "migration", res.Name)
+		}
+	}
+	return s, nil
+}
+
+func (s *SQLite) Close() error {
+	s.closed.Store(true)
+	return s.db.Close()
+}
+
+func (s *SQLite) IsOpen() bool {
+	return !s.closed.Load()
+}
+
+// Migrate applies the schema migrations that haven't been applied yet. Since
+// OpenSQLite already applies them, this only reports that they were.
+func (s *SQLite) Migrate() ([]MigrationResult, error) {
+	_, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS migrations (
+	name TEXT PRIMARY KEY,
+	applied_at INTEGER NOT NULL
+)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrations table: %w", err)
+	}
+
+	var out []MigrationResult
+	for _, m := range sqliteMigrations {
+		applied, err := s.applyMigration(m.name, m.stmt)
+		if err != nil {
+			return out, fmt.Errorf("failed to run migration %q: %w", m.name, err)
+		}
+		out = append(out, MigrationResult{Name: m.name, AlreadyApplied: !applied})
+	}
+	return out, nil
+}
+
+// applyMigration runs the statement and records it as applied, unless it was
+// already, in which case it returns false.
+func (s *SQLite) applyMigration(name, stmt string) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Claim the migration first, so another process opening the database at
+	// the same time waits for this one instead of running it too.
+	res, err := tx.Exec(`INSERT INTO migrations (name, applied_at) VALUES (?, ?) ON CONFLICT DO NOTHING`, name, time.Now().Unix())
+	if err != nil {
+		return false, fmt.Errorf("failed to record migration: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return false, fmt.Errorf("failed to record migration: %w", err)
+	} else if n == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(stmt); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return true, nil
+}
+
+// sqliteDate returns the date as YYYYMMDD, which is how dates are stored.
+func sqliteDate(d Date) int64 {
+	return int64(d.Year)*10000 + int64(d.Month)*100 + int64(d.Day)
+}
+
+func dateFromSQLite(n int64) Date {
+	return Date{Year: int32(n / 10000), Month: time.Month(n / 100 % 100), Day: int8(n % 100)}
+}
+
+func (s *SQLite) AddGame(channel string, date Date, game *srordle.Game) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	buf, err := encodeGame(game)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`INSERT INTO games (channel, date, game) VALUES (?, ?, ?)
+ON CONFLICT (channel, date) DO UPDATE SET game = excluded.game`, channel, sqliteDate(date), buf)
+	if err != nil {
+		return fmt.Errorf("failed to set game: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) ChangeGame(c *GameChange) error {
+	if err := ValidateChannel(c.Channel); err != nil {
+		return err
+	}
+	gameBuf, err := encodeGame(c.New)
+	if err != nil {
+		return err
+	}
+	changeBuf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO games (channel, date, game) VALUES (?, ?, ?)
+ON CONFLICT (channel, date) DO UPDATE SET game = excluded.game`, c.Channel, sqliteDate(c.Date), gameBuf)
+	if err != nil {
+		return fmt.Errorf("failed to set game: %w", err)
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO game_changes (key, change) VALUES (?, ?)`, gameChangeKey(c), changeBuf); err != nil {
+		return fmt.Errorf("failed to add game change: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) CreateGame(channel string, date Date, game *srordle.Game) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+	buf, err := encodeGame(game)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`INSERT INTO games (channel, date, game) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`, channel, sqliteDate(date), buf)
+	if err != nil {
+		return fmt.Errorf("failed to create game: %w", err)
+	}
+	return errIfNoRows(res, ErrGameExists)
+}
+
+func (s *SQLite) DeleteGame(channel string, date Date) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	res, err := s.db.Exec(`DELETE FROM games WHERE channel = ? AND date = ?`, channel, sqliteDate(date))
+	if err != nil {
+		return fmt.Errorf("failed to delete game: %w", err)
+	}
+	return errIfNoRows(res, ErrGameNotFound)
+}
+
+// errIfNoRows returns errNoRows if the statement didn't change any rows.
+func errIfNoRows(res sql.Result, errNoRows error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to count changed rows: %w", err)
+	}
+	if n == 0 {
+		return errNoRows
+	}
+	return nil
+}
+
+func (s *SQLite) Game(channel string, date Date) (*srordle.Game, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	err := s.db.QueryRow(`SELECT game FROM games WHERE channel = ? AND date = ?`, channel, sqliteDate(date)).Scan(&buf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrGameNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load game: %w", err)
+	}
+
+	g, _, err := decodeGame(buf)
+	return g, err
+}
+
+func (s *SQLite) Games(channel string, from, to Date) ([]DatedGame, error) {
+	return collectGames(channel, from, to, s.EachGame)
+}
+
+func (s *SQLite) EachGame(channel string, from, to Date, fn func(DatedGame) error) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	rows, err := s.db.Query(
+		`SELECT date, game FROM games WHERE channel = ? AND date BETWEEN ? AND ? ORDER BY date`,
+		channel, sqliteDate(from), sqliteDate(to))
+	if err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			n   int64
+			buf []byte
+		)
+		if err := rows.Scan(&n, &buf); err != nil {
+			return fmt.Errorf("failed to load game: %w", err)
+		}
+		date := dateFromSQLite(n)
+		g, _, err := decodeGame(buf)
+		if err != nil {
+			return fmt.Errorf("failed to load game for %s: %w", date, err)
+		}
+		if err := fn(DatedGame{Date: date, Game: g}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load games: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) ScheduledGames(channel string) ([]DatedGame, error) {
+	return s.Games(channel, minDate, maxDate)
+}
+
+func (s *SQLite) LastScheduledDate(channel string) (Date, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return Date{}, err
+	}
+
+	var n sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(date) FROM games WHERE channel = ?`, channel).Scan(&n); err != nil {
+		return Date{}, fmt.Errorf("failed to find last game: %w", err)
+	}
+	if !n.Valid {
+		return Date{}, ErrGameNotFound
+	}
+	return dateFromSQLite(n.Int64), nil
+}
+
+func (s *SQLite) Channels() ([]string, error) {
+	rows, err := s.db.Query(`SELECT DISTINCT channel FROM games ORDER BY channel`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channels: %w", err)
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var ch string
+		if err := rows.Scan(&ch); err != nil {
+			return nil, fmt.Errorf("failed to load channel: %w", err)
+		}
+		out = append(out, ch)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load channels: %w", err)
+	}
+	return out, nil
+}
+
+func (s *SQLite) AddGameChange(c *GameChange) error {
+	buf, err := encodeGameChange(c)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO game_changes (key, change) VALUES (?, ?)`, gameChangeKey(c), buf); err != nil {
+		return fmt.Errorf("failed to add game change: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) GameChanges() ([]*GameChange, error) {
+	return sqliteValues(s, `SELECT change FROM game_changes ORDER BY key`, decodeGameChange)
+}
+
+func (s *SQLite) AddDeadLetter(dl *DeadLetter) error {
+	buf, err := encodeDeadLetter(dl)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO dead_letters (key, dead_letter) VALUES (?, ?)`, deadLetterKey(dl), buf); err != nil {
+		return fmt.Errorf("failed to add dead letter: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) DeadLetters() ([]*DeadLetter, error) {
+	return sqliteValues(s, `SELECT dead_letter FROM dead_letters ORDER BY key`, decodeDeadLetter)
+}
+
+func (s *SQLite) AddShare(id string, res *SharedResult) error {
+	buf, err := encodeShare(res)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO shares (id, result) VALUES (?, ?)`, id, buf); err != nil {
+		return fmt.Errorf("failed to add shared result: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Share(id string) (*SharedResult, error) {
+	var buf []byte
+	err := s.db.QueryRow(`SELECT result FROM shares WHERE id = ?`, id).Scan(&buf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrShareNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load shared result: %w", err)
+	}
+
+	res, _, err := decodeShare(buf)
+	return res, err
+}
+
+func (s *SQLite) AddSession(sess *Session) error {
+	buf, err := encodeSession(sess)
+	if err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO sessions (id, session) VALUES (?, ?)`, sess.ID, buf); err != nil {
+		return fmt.Errorf("failed to add session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Session(id string) (*Session, error) {
+	var buf []byte
+	err := s.db.QueryRow(`SELECT session FROM sessions WHERE id = ?`, id).Scan(&buf)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrSessionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	sess, _, err := decodeSession(buf)
+	return sess, err
+}
+
+func (s *SQLite) DeleteSession(id string) error {
+	res, err := s.db.Exec(`DELETE FROM sessions WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return errIfNoRows(res, ErrSessionNotFound)
+}
+
+func (s *SQLite) RecordResult(channel string, date Date, won bool, guesses int) error {
+	if err := ValidateChannel(channel); err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	wins := 0
+	if won {
+		wins = 1
+	}
+	_, err = tx.Exec(`INSERT INTO stats (channel, date, played, won) VALUES (?, ?, 1, ?)
+ON CONFLICT (channel, date) DO UPDATE SET played = played + 1, won = won + excluded.won`, channel, sqliteDate(date), wins)
+	if err != nil {
+		return fmt.Errorf("failed to record result: %w", err)
+	}
+	if won {
+		_, err = tx.Exec(`INSERT INTO stats_win_guesses (channel, date, guesses, wins) VALUES (?, ?, ?, 1)
+ON CONFLICT (channel, date, guesses) DO UPDATE SET wins = wins + 1`, channel, sqliteDate(date), guesses)
+		if err != nil {
+			return fmt.Errorf("failed to record win: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLite) Stats(channel string, date Date) (*Stats, error) {
+	if err := ValidateChannel(channel); err != nil {
+		return nil, err
+	}
+
+	// Read in a transaction, so the counts are from the same results.
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	st := &Stats{Channel: channel, Date: date}
+	err = tx.QueryRow(`SELECT played, won FROM stats WHERE channel = ? AND date = ?`, channel, sqliteDate(date)).Scan(&st.Played, &st.Won)
+	if errors.Is(err, sql.ErrNoRows) {
+		return st, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to load stats: %w", err)
+	}
+
+	rows, err := tx.Query(`SELECT guesses, wins FROM stats_win_guesses WHERE channel = ? AND date = ?`, channel, sqliteDate(date))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wins: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var guesses, wins int
+		if err := rows.Scan(&guesses, &wins); err != nil {
+			return nil, fmt.Errorf("failed to load wins: %w", err)
+		}
+		if st.WinGuesses == nil {
+			st.WinGuesses = make(map[int]int)
+		}
+		st.WinGuesses[guesses] = wins
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load wins: %w", err)
+	}
+	return st, nil
+}
+
+// sqliteValues runs a query for a single column of encoded values and decodes
+// each of them.
+func sqliteValues[T any](s *SQLite, query string, decode func([]byte) (T, int, error)) ([]T, error) {
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer rows.Close()
+
+	var out []T
+	for rows.Next() {
+		var buf []byte
+		if err := rows.Scan(&buf); err != nil {
+			return nil, fmt.Errorf("failed to load row: %w", err)
+		}
+		v, _, err := decode(buf)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load rows: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/bcspragu/srordle/srordle"
+)
+
+// Store is where games, player sessions, stats, and the records kept about
+// them are stored. DB stores them in Badger, SQLite stores them in a SQLite
+// database, which more than one process can use at once, and Memory keeps
+// them in memory for tests.
+type Store interface {
+	Close() error
+	// IsOpen returns false once the store has been closed.
+	IsOpen() bool
+	// Migrate applies every migration that hasn't been applied to the store
+	// yet, in order.
+	Migrate() ([]MigrationResult, error)
+
+	// AddGame sets the game for the given channel and date, replacing any
+	// existing game.
+	AddGame(channel string, date Date, game *srordle.Game) error
+	// ChangeGame sets c.New as the game for c's channel and date, replacing any
+	// existing game, and records c, in one transaction.
+	ChangeGame(c *GameChange) error
+	// CreateGame sets the game for the given channel and date, returning
+	// ErrGameExists if the date already has one.
+	CreateGame(channel string, date Date, game *srordle.Game) error
+	// DeleteGame removes the game for the given channel and date, returning
+	// ErrGameNotFound if there wasn't one.
+	DeleteGame(channel string, date Date) error
+	// Game returns the game for the given channel and date, or ErrGameNotFound
+	// if there isn't one.
+	Game(channel string, date Date) (*srordle.Game, error)
+	// Games returns the games in the channel scheduled from from to to,
+	// inclusive, in date order.
+	Games(channel string, from, to Date) ([]DatedGame, error)
+	// EachGame calls fn with each game in the channel scheduled from from to
+	// to, inclusive, in date order, stopping at the first error fn returns.
+	EachGame(channel string, from, to Date, fn func(DatedGame) error) error
+	// ScheduledGames returns every game in the channel in date order.
+	ScheduledGames(channel string) ([]DatedGame, error)
+	// LastScheduledDate returns the date of the last game scheduled in the
+	// channel, or ErrGameNotFound if it has none.
+	LastScheduledDate(channel string) (Date, error)
+	// Channels returns the name of every channel with at least one game,
+	// sorted.
+	Channels() ([]string, error)
+
+	// AddGameChange records a change to a game.
+	AddGameChange(c *GameChange) error
+	// GameChanges returns every recorded game change, oldest first.
+	GameChanges() ([]*GameChange, error)
+	// AddDeadLetter records a webhook delivery that failed permanently.
+	AddDeadLetter(dl *DeadLetter) error
+	// DeadLetters returns every recorded dead letter, oldest first.
+	DeadLetters() ([]*DeadLetter, error)
+	// AddShare stores a shared result under the given ID, replacing any
+	// existing one.
+	AddShare(id string, res *SharedResult) error
+	// Share returns the shared result with the given ID, or ErrShareNotFound.
+	Share(id string) (*SharedResult, error)
+
+	// AddSession stores the session under its ID, replacing any existing one.
+	AddSession(sess *Session) error
+	// Session returns the session with the given ID, or ErrSessionNotFound.
+	Session(id string) (*Session, error)
+	// DeleteSession removes the session with the given ID, returning
+	// ErrSessionNotFound if there wasn't one.
+	DeleteSession(id string) error
+	// RecordResult adds a finished game to the stats for the channel and date.
+	// Guesses is how many guesses the player made, and is only counted for
+	// wins.
+	RecordResult(channel string, date Date, won bool, guesses int) error
+	// Stats returns the stats for the channel and date, which are empty if no
+	// results have been recorded.
+	Stats(channel string, date Date) (*Stats, error)
+}
+
+var (
+	_ Store = (*DB)(nil)
+	_ Store = (*SQLite)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// The drivers that OpenStore supports.
+const (
+	DriverBadger = "badger"
+	DriverSQLite = "sqlite"
+)
+
+// Drivers are the drivers that OpenStore supports, for validating config.
+var Drivers = []string{DriverBadger, DriverSQLite}
+
+// OpenStore opens the store with the given driver at path, which is a
+// directory for Badger and a file for SQLite. An empty driver means Badger,
+// which was the only store before there were drivers.
+func OpenStore(driver, path string) (Store, error) {
+	switch driver {
+	case DriverBadger, "":
+		return Open(path)
+	case DriverSQLite:
+		return OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// collectGames returns the games that eachGame passes to its callback, for
+// implementing Games with EachGame.
+func collectGames(channel string, from, to Date, eachGame func(string, Date, Date, func(DatedGame) error) error) ([]DatedGame, error) {
+	var out []DatedGame
+	err := eachGame(channel, from, to, func(dg DatedGame) error {
+		out = append(out, dg)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,374 @@
+package db
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestStores runs the same checks against every Store, so they behave the same.
+func TestStores(t *testing.T) {
+	stores := []struct {
+		name string
+		open func(t *testing.T) Store
+	}{
+		{"badger", func(t *testing.T) Store { return openTestDB(t) }},
+		{"sqlite", func(t *testing.T) Store { return openTestSQLite(t, filepath.Join(t.TempDir(), "srordle.db")) }},
+		{"memory", func(t *testing.T) Store { return NewMemory() }},
+	}
+	for _, st := range stores {
+		t.Run(st.name, func(t *testing.T) {
+			t.Run("Games", func(t *testing.T) { testStoreGames(t, st.open(t)) })
+			t.Run("Records", func(t *testing.T) { testStoreRecords(t, st.open(t)) })
+			t.Run("Sessions", func(t *testing.T) { testStoreSessions(t, st.open(t)) })
+			t.Run("Stats", func(t *testing.T) { testStoreStats(t, st.open(t)) })
+			t.Run("Close", func(t *testing.T) {
+				s := st.open(t)
+				if !s.IsOpen() {
+					t.Error("IsOpen = false before Close")
+				}
+				s.Close()
+				if s.IsOpen() {
+					t.Error("IsOpen = true after Close")
+				}
+			})
+		})
+	}
+}
+
+func testStoreGames(t *testing.T, s Store) {
+	date := Date{Year: 2022, Month: time.December, Day: 30}
+
+	if _, err := s.LastScheduledDate(DefaultChannel); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("LastScheduledDate with no games returned %v, want %v", err, ErrGameNotFound)
+	}
+	if err := s.AddGame(DefaultChannel, date, testGame("detract")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	if err := s.CreateGame(DefaultChannel, date, testGame("cottage")); !errors.Is(err, ErrGameExists) {
+		t.Errorf("CreateGame over an existing game returned %v, want %v", err, ErrGameExists)
+	}
+	if err := s.CreateGame(DefaultChannel, date.AddDays(3), testGame("carrots")); err != nil {
+		t.Fatalf("CreateGame: %v", err)
+	}
+	if err := s.AddGame(DefaultChannel, date.AddDays(1), testGame("cottage")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	if err := s.AddGame("work", date.AddDays(10), testGame("working")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	if err := s.AddGame("Not A Slug", date, testGame("detract")); err == nil {
+		t.Error("AddGame with an invalid channel didn't return an error")
+	}
+
+	// Replacing a game changes it.
+	if err := s.AddGame(DefaultChannel, date.AddDays(1), testGame("example")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	g, err := s.Game(DefaultChannel, date.AddDays(1))
+	if err != nil {
+		t.Fatalf("Game: %v", err)
+	}
+	if diff := cmp.Diff(testGame("example"), g); diff != "" {
+		t.Errorf("unexpected game (-want +got)\n%s", diff)
+	}
+	if _, err := s.Game("work", date); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("Game in another channel returned %v, want %v", err, ErrGameNotFound)
+	}
+
+	gameStrings := func(games []DatedGame) []string {
+		var out []string
+		for _, dg := range games {
+			out = append(out, dg.Date.String()+" "+dg.Game.TargetWord)
+		}
+		return out
+	}
+	got, err := s.Games(DefaultChannel, date.AddDays(1), date.AddDays(3))
+	if err != nil {
+		t.Fatalf("Games: %v", err)
+	}
+	if diff := cmp.Diff([]string{"2022-12-31 example", "2023-01-02 carrots"}, gameStrings(got)); diff != "" {
+		t.Errorf("unexpected games (-want +got)\n%s", diff)
+	}
+	got, err = s.ScheduledGames(DefaultChannel)
+	if err != nil {
+		t.Fatalf("ScheduledGames: %v", err)
+	}
+	want := []string{"2022-12-30 detract", "2022-12-31 example", "2023-01-02 carrots"}
+	if diff := cmp.Diff(want, gameStrings(got)); diff != "" {
+		t.Errorf("unexpected scheduled games (-want +got)\n%s", diff)
+	}
+
+	// EachGame stops at the first error.
+	errStop := errors.New("stop")
+	calls := 0
+	err = s.EachGame(DefaultChannel, date, date.AddDays(3), func(DatedGame) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("EachGame returned %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+
+	last, err := s.LastScheduledDate(DefaultChannel)
+	if err != nil {
+		t.Fatalf("LastScheduledDate: %v", err)
+	}
+	if want := date.AddDays(3); last != want {
+		t.Errorf("LastScheduledDate = %s, want %s", last, want)
+	}
+	channels, err := s.Channels()
+	if err != nil {
+		t.Fatalf("Channels: %v", err)
+	}
+	if diff := cmp.Diff([]string{DefaultChannel, "work"}, channels); diff != "" {
+		t.Errorf("unexpected channels (-want +got)\n%s", diff)
+	}
+
+	if err := s.DeleteGame(DefaultChannel, date); err != nil {
+		t.Fatalf("DeleteGame: %v", err)
+	}
+	if err := s.DeleteGame(DefaultChannel, date); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("DeleteGame of a deleted game returned %v, want %v", err, ErrGameNotFound)
+	}
+	if _, err := s.Game(DefaultChannel, date); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("Game after delete returned %v, want %v", err, ErrGameNotFound)
+	}
+}
+
+func testStoreRecords(t *testing.T, s Store) {
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	date := ToDate(now)
+
+	// Added out of order, and returned oldest first.
+	changes := []*GameChange{
+		{Channel: DefaultChannel, Date: date, New: testGame("cottage"), ChangedBy: "srini", ChangedAt: now.Add(time.Minute)},
+		{Channel: "work", Date: date, Old: testGame("cottage"), New: testGame("detract"), Forced: true, ChangedBy: "srini", ChangedAt: now},
+	}
+	for _, c := range changes {
+		if err := s.AddGameChange(c); err != nil {
+			t.Fatalf("AddGameChange: %v", err)
+		}
+	}
+	gotChanges, err := s.GameChanges()
+	if err != nil {
+		t.Fatalf("GameChanges: %v", err)
+	}
+	if diff := cmp.Diff([]*GameChange{changes[1], changes[0]}, gotChanges); diff != "" {
+		t.Errorf("unexpected game changes (-want +got)\n%s", diff)
+	}
+
+	// ChangeGame sets the game and records the change together.
+	change := &GameChange{Channel: "work", Date: date, Old: testGame("detract"), New: testGame("cottage"), ChangedBy: "srini", ChangedAt: now.Add(2 * time.Minute)}
+	if err := s.ChangeGame(change); err != nil {
+		t.Fatalf("ChangeGame: %v", err)
+	}
+	game, err := s.Game("work", date)
+	if err != nil {
+		t.Fatalf("Game: %v", err)
+	}
+	if diff := cmp.Diff(change.New, game); diff != "" {
+		t.Errorf("unexpected game after ChangeGame (-want +got)\n%s", diff)
+	}
+	gotChanges, err = s.GameChanges()
+	if err != nil {
+		t.Fatalf("GameChanges: %v", err)
+	}
+	if diff := cmp.Diff([]*GameChange{changes[1], changes[0], change}, gotChanges); diff != "" {
+		t.Errorf("unexpected game changes after ChangeGame (-want +got)\n%s", diff)
+	}
+	if err := s.ChangeGame(&GameChange{Channel: "no spaces", Date: date, New: testGame("cottage"), ChangedAt: now}); err == nil {
+		t.Error("ChangeGame with an invalid channel succeeded")
+	}
+
+	dls := []*DeadLetter{
+		{ID: "b", URL: "https://example.com/hook", Event: "game.live", Payload: []byte(`{}`), Attempts: 3, LastError: "timeout", FailedAt: now.Add(time.Minute)},
+		{ID: "a", URL: "https://example.com/hook", Event: "game.live", Payload: []byte(`{}`), Attempts: 3, LastError: "timeout", FailedAt: now},
+	}
+	for _, dl := range dls {
+		if err := s.AddDeadLetter(dl); err != nil {
+			t.Fatalf("AddDeadLetter: %v", err)
+		}
+	}
+	gotDLs, err := s.DeadLetters()
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if diff := cmp.Diff([]*DeadLetter{dls[1], dls[0]}, gotDLs); diff != "" {
+		t.Errorf("unexpected dead letters (-want +got)\n%s", diff)
+	}
+
+	share := &SharedResult{Channel: DefaultChannel, Date: date, Won: true, Rows: [][]srordle.LetterStatus{{srordle.Correct}}, CreatedAt: now}
+	if _, err := s.Share("0123456789abcdef"); !errors.Is(err, ErrShareNotFound) {
+		t.Errorf("Share before AddShare returned %v, want %v", err, ErrShareNotFound)
+	}
+	if err := s.AddShare("0123456789abcdef", share); err != nil {
+		t.Fatalf("AddShare: %v", err)
+	}
+	gotShare, err := s.Share("0123456789abcdef")
+	if err != nil {
+		t.Fatalf("Share: %v", err)
+	}
+	if diff := cmp.Diff(share, gotShare); diff != "" {
+		t.Errorf("unexpected shared result (-want +got)\n%s", diff)
+	}
+}
+
+func testStoreSessions(t *testing.T, s Store) {
+	now := time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)
+	sess := &Session{
+		ID:      "0123456789abcdef",
+		Channel: DefaultChannel,
+		Date:    ToDate(now),
+		Progress: srordle.Progress{
+			Guesses:          []srordle.Guess{{Words: []string{"cot", "age"}, GuessedAt: now}},
+			FullAttemptsLeft: 2,
+		},
+		UpdatedAt: now,
+	}
+
+	if _, err := s.Session(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Session before AddSession returned %v, want %v", err, ErrSessionNotFound)
+	}
+	if err := s.AddSession(sess); err != nil {
+		t.Fatalf("AddSession: %v", err)
+	}
+
+	// Adding it again replaces it.
+	sess.Progress.Guesses = append(sess.Progress.Guesses, srordle.Guess{Words: []string{"detract"}, GuessedAt: now.Add(time.Minute), RequestedFull: true})
+	sess.Progress.FullAttemptsLeft = 1
+	sess.Progress.Won = true
+	sess.UpdatedAt = now.Add(time.Minute)
+	if err := s.AddSession(sess); err != nil {
+		t.Fatalf("AddSession: %v", err)
+	}
+	got, err := s.Session(sess.ID)
+	if err != nil {
+		t.Fatalf("Session: %v", err)
+	}
+	if diff := cmp.Diff(sess, got); diff != "" {
+		t.Errorf("unexpected session (-want +got)\n%s", diff)
+	}
+
+	if err := s.DeleteSession(sess.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if err := s.DeleteSession(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("DeleteSession of a deleted session returned %v, want %v", err, ErrSessionNotFound)
+	}
+	if _, err := s.Session(sess.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Session after delete returned %v, want %v", err, ErrSessionNotFound)
+	}
+}
+
+func testStoreStats(t *testing.T, s Store) {
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+
+	got, err := s.Stats(DefaultChannel, date)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if diff := cmp.Diff(&Stats{Channel: DefaultChannel, Date: date}, got); diff != "" {
+		t.Errorf("unexpected stats before any results (-want +got)\n%s", diff)
+	}
+
+	results := []struct {
+		channel string
+		date    Date
+		won     bool
+		guesses int
+	}{
+		{DefaultChannel, date, true, 3},
+		{DefaultChannel, date, true, 3},
+		{DefaultChannel, date, true, 5},
+		{DefaultChannel, date, false, 6},
+		// Other channels and dates are counted separately.
+		{"work", date, true, 1},
+		{DefaultChannel, date.AddDays(1), false, 4},
+	}
+	for _, res := range results {
+		if err := s.RecordResult(res.channel, res.date, res.won, res.guesses); err != nil {
+			t.Fatalf("RecordResult: %v", err)
+		}
+	}
+	if err := s.RecordResult("Not A Slug", date, true, 1); err == nil {
+		t.Error("RecordResult with an invalid channel didn't return an error")
+	}
+
+	got, err = s.Stats(DefaultChannel, date)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	want := &Stats{Channel: DefaultChannel, Date: date, Played: 4, Won: 3, WinGuesses: map[int]int{3: 2, 5: 1}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected stats (-want +got)\n%s", diff)
+	}
+	got, err = s.Stats(DefaultChannel, date.AddDays(1))
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if diff := cmp.Diff(&Stats{Channel: DefaultChannel, Date: date.AddDays(1), Played: 1}, got); diff != "" {
+		t.Errorf("unexpected stats for a loss (-want +got)\n%s", diff)
+	}
+
+	// Results recorded at the same time are all counted.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.RecordResult("race", date, true, 2); err != nil {
+				t.Errorf("RecordResult: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	got, err = s.Stats("race", date)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if got.Played != 10 || got.WinGuesses[2] != 10 {
+		t.Errorf("got %d played and %d won in 2 after 10 concurrent wins, want 10", got.Played, got.WinGuesses[2])
+	}
+}
+
+// TestSQLiteSharedFile checks that two stores can use the same SQLite file at
+// once, like the server and CLI do.
+func TestSQLiteSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "srordle.db")
+	server := openTestSQLite(t, path)
+	cli := openTestSQLite(t, path)
+	date := Date{Year: 2022, Month: time.August, Day: 20}
+
+	if err := cli.AddGame(DefaultChannel, date, testGame("detract")); err != nil {
+		t.Fatalf("AddGame: %v", err)
+	}
+	assertWord(t, server, DefaultChannel, date, "detract")
+
+	// Opening it again doesn't rerun migrations.
+	got, err := cli.Migrate()
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	want := []MigrationResult{
+		{Name: "0001-create-tables", AlreadyApplied: true},
+		{Name: "0002-create-sessions-and-stats", AlreadyApplied: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected migration results (-want +got)\n%s", diff)
+	}
+}
+
+func openTestSQLite(t *testing.T, path string) *SQLite {
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
@@ -0,0 +1,232 @@
+// Package gamestate implements tokens that carry a player's progress through a
+// game. Tokens are signed with HMAC-SHA256 and can optionally be encrypted, so
+// the server can enforce the rules of a game without storing anything.
+package gamestate
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+)
+
+const (
+	versionSigned    = "v1"
+	versionEncrypted = "v1e"
+)
+
+// ErrInvalidToken is returned when a token is malformed, was signed with an
+// unknown key, or has been tampered with.
+var ErrInvalidToken = errors.New("invalid game state token")
+
+// State is the information carried in a token.
+type State struct {
+	// Channel is the channel the game is in, and is empty for the default
+	// channel, which is also what tokens from before channels have.
+	Channel  string `json:",omitempty"`
+	Date     db.Date
+	Progress srordle.Progress
+	IssuedAt time.Time
+}
+
+// Key is a secret used to sign and encrypt tokens. The ID is included in each
+// token so that old keys can still be used to verify tokens after rotation.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys parses a comma-separated list of <id>:<hex secret> pairs, like
+// "2:abcd...,1:0123...". The first key is used for issuing new tokens.
+func ParseKeys(in string) ([]Key, error) {
+	var keys []Key
+	for _, kv := range strings.Split(in, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		id, secHex, ok := strings.Cut(kv, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("key %q wasn't of the form <id>:<hex secret>", kv)
+		}
+		if strings.ContainsAny(id, ".") {
+			return nil, fmt.Errorf("key ID %q can't contain '.'", id)
+		}
+		sec, err := hex.DecodeString(secHex)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode secret for key %q: %w", id, err)
+		}
+		keys = append(keys, Key{ID: id, Secret: sec})
+	}
+	return keys, nil
+}
+
+type derivedKey struct {
+	mac []byte
+	enc []byte
+}
+
+// Codec encodes and decodes game state tokens.
+type Codec struct {
+	primary string
+	keys    map[string]derivedKey
+	encrypt bool
+}
+
+// NewCodec returns a Codec that issues tokens with the first key, and accepts
+// tokens signed with any of the given keys. If encrypt is true, issued tokens
+// are also encrypted with AES-GCM, so players can't read the state. Either
+// kind of token is accepted regardless of the encrypt setting.
+func NewCodec(keys []Key, encrypt bool) (*Codec, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+
+	c := &Codec{
+		primary: keys[0].ID,
+		keys:    make(map[string]derivedKey),
+		encrypt: encrypt,
+	}
+	for _, k := range keys {
+		if len(k.Secret) < 32 {
+			return nil, fmt.Errorf("secret for key %q was %d bytes, need at least 32", k.ID, len(k.Secret))
+		}
+		if _, ok := c.keys[k.ID]; ok {
+			return nil, fmt.Errorf("duplicate key ID %q", k.ID)
+		}
+		c.keys[k.ID] = derivedKey{
+			mac: derive(k.Secret, "srordle mac"),
+			enc: derive(k.Secret, "srordle enc"),
+		}
+	}
+	return c, nil
+}
+
+// derive returns a sub-key for a specific purpose, so that the same secret
+// isn't used directly for both signing and encryption.
+func derive(secret []byte, purpose string) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(purpose))
+	return h.Sum(nil)
+}
+
+// Encode returns a token holding the given state.
+func (c *Codec) Encode(s *State) (string, error) {
+	dat, err := json.Marshal(s)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	key := c.keys[c.primary]
+	version := versionSigned
+	if c.encrypt {
+		version = versionEncrypted
+		if dat, err = seal(key.enc, dat); err != nil {
+			return "", fmt.Errorf("failed to encrypt state: %w", err)
+		}
+	}
+
+	msg := version + "." + c.primary + "." + base64.RawURLEncoding.EncodeToString(dat)
+	return msg + "." + base64.RawURLEncoding.EncodeToString(sign(key.mac, msg)), nil
+}
+
+// Decode verifies the given token and returns the state it holds.
+func (c *Codec) Decode(tok string) (*State, error) {
+	idx := strings.LastIndex(tok, ".")
+	if idx == -1 {
+		return nil, ErrInvalidToken
+	}
+	msg, sigStr := tok[:idx], tok[idx+1:]
+
+	parts := strings.Split(msg, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	version, keyID, payload := parts[0], parts[1], parts[2]
+
+	key, ok := c.keys[keyID]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(sigStr)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if !hmac.Equal(sig, sign(key.mac, msg)) {
+		return nil, ErrInvalidToken
+	}
+
+	dat, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	switch version {
+	case versionSigned:
+		// Nothing to do.
+	case versionEncrypted:
+		if dat, err = open(key.enc, dat); err != nil {
+			return nil, ErrInvalidToken
+		}
+	default:
+		return nil, ErrInvalidToken
+	}
+
+	var s State
+	if err := json.Unmarshal(dat, &s); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state: %w", err)
+	}
+	return &s, nil
+}
+
+func sign(key []byte, msg string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(msg))
+	return h.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,127 @@
+package gamestate
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		c := newCodec(t, encrypt, testKey("1", 'a'))
+
+		want := testState()
+		tok, err := c.Encode(want)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if encrypt && strings.Contains(tok, "eyJ") {
+			t.Errorf("encrypted token %q appears to contain plaintext JSON", tok)
+		}
+
+		got, err := c.Decode(tok)
+		if err != nil {
+			t.Fatalf("Decode: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected state (-want +got)\n%s", diff)
+		}
+	}
+}
+
+func TestTampered(t *testing.T) {
+	for _, encrypt := range []bool{false, true} {
+		c := newCodec(t, encrypt, testKey("1", 'a'))
+
+		tok, err := c.Encode(testState())
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+
+		// Flip a character in the middle of the payload.
+		bs := []byte(tok)
+		i := len(bs) / 2
+		if bs[i] == 'A' {
+			bs[i] = 'B'
+		} else {
+			bs[i] = 'A'
+		}
+
+		if _, err := c.Decode(string(bs)); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("Decode of tampered token = %v, want %v", err, ErrInvalidToken)
+		}
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldKey, newKey := testKey("1", 'a'), testKey("2", 'b')
+
+	oldCodec := newCodec(t, true, oldKey)
+	tok, err := oldCodec.Encode(testState())
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+
+	// After rotation, tokens from the old key are still accepted.
+	rotated := newCodec(t, true, newKey, oldKey)
+	if _, err := rotated.Decode(tok); err != nil {
+		t.Fatalf("Decode with rotated keys: %v", err)
+	}
+
+	// Once the old key is removed, they aren't.
+	removed := newCodec(t, true, newKey)
+	if _, err := removed.Decode(tok); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Decode with old key removed = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	got, err := ParseKeys("2:0a0b, 1:ff")
+	if err != nil {
+		t.Fatalf("ParseKeys: %v", err)
+	}
+	want := []Key{
+		{ID: "2", Secret: []byte{0x0a, 0x0b}},
+		{ID: "1", Secret: []byte{0xff}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected keys (-want +got)\n%s", diff)
+	}
+
+	for _, in := range []string{"nocolon", ":abcd", "1:zz", "a.b:abcd"} {
+		if _, err := ParseKeys(in); err == nil {
+			t.Errorf("ParseKeys(%q) returned no error", in)
+		}
+	}
+}
+
+func newCodec(t *testing.T, encrypt bool, keys ...Key) *Codec {
+	c, err := NewCodec(keys, encrypt)
+	if err != nil {
+		t.Fatalf("NewCodec: %v", err)
+	}
+	return c
+}
+
+func testKey(id string, b byte) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte{b}, 32)}
+}
+
+func testState() *State {
+	return &State{
+		Date: db.Date{Year: 2022, Month: time.August, Day: 20},
+		Progress: srordle.Progress{
+			Guesses: []srordle.Guess{
+				{Words: []string{"cottage"}, GuessedAt: time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC)},
+			},
+			FullAttemptsLeft: 2,
+		},
+		IssuedAt: time.Date(2022, time.August, 20, 12, 0, 0, 0, time.UTC),
+	}
+}
@@ -0,0 +1,162 @@
+// Package logging sets up structured logging with log/slog, shared by the
+// server, the CLI, and the database. Loggers created here redact target words,
+// so that logs never spoil a puzzle.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// TargetWordKey is the attribute key for target words. Any attribute with this
+// key is redacted, wherever it appears.
+const TargetWordKey = "target_word"
+
+// ParseLevel parses a log level, one of debug, info, warn, or error.
+func ParseLevel(in string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(in)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q, should be one of debug, info, warn, or error", in)
+	}
+	return l, nil
+}
+
+// New returns a logger that writes to w at the given level, in the given
+// format, which is either "text" or "json".
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{
+		Level:       lvl,
+		ReplaceAttr: redactTargetWords,
+	}
+
+	switch strings.ToLower(format) {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q, should be one of text or json", format)
+	}
+}
+
+func redactTargetWords(_ []string, a slog.Attr) slog.Attr {
+	if a.Key == TargetWordKey {
+		return slog.String(a.Key, redacted)
+	}
+	return a
+}
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+)
+
+// FromContext returns the logger for the given context, which includes the
+// request ID for contexts from HTTP requests. If there isn't one, it returns
+// the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// RequestID returns the ID of the request the context belongs to, or an empty
+// string if there isn't one.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// RequestIDHeader is used to pass in an existing request ID, e.g. from a
+// reverse proxy, and is set on all responses.
+const RequestIDHeader = "X-Request-ID"
+
+// Middleware assigns each request an ID, attaches a logger with that ID to the
+// request context, and logs each request once it has been served.
+func Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		logger := slog.Default().With("request_id", id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		ctx = context.WithValue(ctx, loggerKey, logger)
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		h.ServeHTTP(sw, r.WithContext(ctx))
+
+		logger.LogAttrs(ctx, slog.LevelDebug, "served request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", sw.status),
+			slog.Duration("duration", time.Since(start)),
+		)
+	})
+}
+
+// validRequestID returns true if a client-provided ID is reasonable to put
+// into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// Extremely unlikely, and not worth failing a request over.
+		return "unknown"
+	}
+	return hex.EncodeToString(b[:])
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wroteHeader {
+		sw.status = code
+		sw.wroteHeader = true
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(b)
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
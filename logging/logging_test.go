@@ -0,0 +1,74 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bcspragu/srordle/srordle"
+)
+
+func TestRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", "json")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	game := &srordle.Game{TargetWord: "detract", Shape: srordle.DefaultShape(), FullAttempts: 2}
+	logger.Info("loaded game", TargetWordKey, "detract")
+	logger.Info("loaded game", "game", game)
+	logger.WithGroup("nested").Info("loaded game", TargetWordKey, "detract")
+
+	if out := buf.String(); strings.Contains(out, "detract") {
+		t.Errorf("target word wasn't redacted from logs:\n%s", out)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", "text")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	var gotID string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = RequestID(r.Context())
+		FromContext(r.Context()).Info("handling")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "abc-123")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if gotID != "abc-123" {
+		t.Errorf("request ID = %q, want %q", gotID, "abc-123")
+	}
+	if got := w.Header().Get(RequestIDHeader); got != "abc-123" {
+		t.Errorf("%s header = %q, want %q", RequestIDHeader, got, "abc-123")
+	}
+	out := buf.String()
+	if n := strings.Count(out, "request_id=abc-123"); n != 2 {
+		t.Errorf("request ID appeared in %d log lines, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, "status=418") {
+		t.Errorf("request log didn't include the status:\n%s", out)
+	}
+
+	// Unreasonable IDs get replaced.
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(RequestIDHeader, "bad id\nwith newline")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if gotID == "" || strings.Contains(gotID, " ") {
+		t.Errorf("invalid request ID wasn't replaced, got %q", gotID)
+	}
+}
@@ -0,0 +1,153 @@
+// Package metrics holds the Prometheus metrics for Srordle. Everything is
+// registered with a single registry, so any binary (the server, the CLI, or
+// some future worker) can define its own metrics with the helpers here and
+// expose them all with Handler.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "srordle"
+
+// Registry is where all Srordle metrics are registered. It also includes the
+// standard Go runtime and process metrics.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Guess outcomes, for use with GuessesTotal.
+const (
+	OutcomeWon         = "won"
+	OutcomeIncorrect   = "incorrect"
+	OutcomeInvalidWord = "invalid_word"
+	OutcomeWrongShape  = "wrong_shape"
+	// OutcomeRejected means the guess wasn't allowed by the rules of the game,
+	// e.g. the game was already over.
+	OutcomeRejected = "rejected"
+)
+
+var (
+	requestsTotal = NewCounterVec("http_requests_total", "Number of HTTP requests, by handler and status code.", "handler", "code")
+
+	requestDuration = NewHistogramVec("http_request_duration_seconds", "Latency of HTTP requests, by handler.", prometheus.DefBuckets, "handler")
+
+	// GuessesTotal counts guesses by outcome, see the Outcome* constants.
+	GuessesTotal = NewCounterVec("guesses_total", "Number of guesses submitted, by outcome.", "outcome")
+
+	// DictionaryLookupDuration observes how long it takes to check if a word is
+	// in the dictionary.
+	DictionaryLookupDuration = NewHistogram("dictionary_lookup_duration_seconds", "Latency of dictionary lookups.", prometheus.ExponentialBuckets(1e-7, 4, 10))
+
+	// PanicsTotal counts panics recovered while serving HTTP requests.
+	PanicsTotal = NewCounter("panics_total", "Number of panics recovered in HTTP handlers.")
+
+	// DBGCTotal counts runs of the database's garbage collection, by result,
+	// which is either "rewritten", "nothing_to_do", or "error".
+	DBGCTotal = NewCounterVec("db_gc_runs_total", "Number of database value log garbage collection runs, by result.", "result")
+
+	// WordListReloadsTotal counts attempts to reload the word lists, by result,
+	// which is either "success" or "error".
+	WordListReloadsTotal = NewCounterVec("word_list_reloads_total", "Number of attempts to reload the dictionary and target word lists, by result.", "result")
+
+	// WebhookDeliveriesTotal counts webhook delivery attempts, by result, which
+	// is either "success", "retry", or "dead_letter".
+	WebhookDeliveriesTotal = NewCounterVec("webhook_deliveries_total", "Number of webhook delivery attempts, by result.", "result")
+)
+
+// NewCounter creates and registers a counter in the srordle namespace.
+func NewCounter(name, help string) prometheus.Counter {
+	c := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	})
+	Registry.MustRegister(c)
+	return c
+}
+
+// NewCounterVec creates and registers a counter with the given labels in the
+// srordle namespace.
+func NewCounterVec(name, help string, labels ...string) *prometheus.CounterVec {
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, labels)
+	Registry.MustRegister(c)
+	return c
+}
+
+// NewHistogram creates and registers a histogram in the srordle namespace.
+func NewHistogram(name, help string, buckets []float64) prometheus.Histogram {
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	})
+	Registry.MustRegister(h)
+	return h
+}
+
+// NewHistogramVec creates and registers a histogram with the given labels in
+// the srordle namespace.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *prometheus.HistogramVec {
+	h := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+		Buckets:   buckets,
+	}, labels)
+	Registry.MustRegister(h)
+	return h
+}
+
+// NewGaugeFunc creates and registers a gauge in the srordle namespace, whose
+// value is computed by calling fn on each scrape.
+func NewGaugeFunc(name, help string, fn func() float64) prometheus.GaugeFunc {
+	g := prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      name,
+		Help:      help,
+	}, fn)
+	Registry.MustRegister(g)
+	return g
+}
+
+// RegisterDBSize registers gauges for the on-disk size of the database, as
+// reported by size.
+func RegisterDBSize(size func() (lsm, vlog int64)) {
+	NewGaugeFunc("db_lsm_size_bytes", "Size of the database's LSM tree files.", func() float64 {
+		lsm, _ := size()
+		return float64(lsm)
+	})
+	NewGaugeFunc("db_vlog_size_bytes", "Size of the database's value log files.", func() float64 {
+		_, vlog := size()
+		return float64(vlog)
+	})
+}
+
+// InstrumentHandler wraps the given handler to record request counts and
+// latencies, labeled with the given handler name.
+func InstrumentHandler(name string, h http.Handler) http.Handler {
+	labels := prometheus.Labels{"handler": name}
+	return promhttp.InstrumentHandlerCounter(
+		requestsTotal.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(requestDuration.MustCurryWith(labels), h),
+	)
+}
+
+// Handler serves all registered metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -16,7 +16,7 @@ const infix = isProd ? '.min' : ''
 export default {
   input: `src/index.ts`,
   output: {
-    file: `dist/index${infix}.js`,
+    file: `web/dist/index${infix}.js`,
     format: 'iife',
     globals: {
       // For making PaperJS work (mostly) as expected.
@@ -0,0 +1,222 @@
+// Package solver provides a bot that plays Srordle games, used to estimate how
+// hard a target word is to find with a given shape.
+package solver
+
+import (
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+)
+
+// Solver plays games by narrowing down a list of candidate target words.
+type Solver struct {
+	dict       *trie.Trie
+	candidates []string
+	// fallback is a dictionary word of each length, for rows where no
+	// candidate can be guessed.
+	fallback map[int]string
+}
+
+// New returns a Solver that only guesses words in the dictionary, and assumes
+// the target word is one of the candidates.
+func New(dict *trie.Trie, candidates []string) *Solver {
+	// Candidates that could never be a target word are skipped, which also
+	// means the rest can be indexed by letter.
+	var cands []string
+	for _, c := range candidates {
+		if len(c) == srordle.WordLength && trie.CheckWord(c) == nil {
+			cands = append(cands, c)
+		}
+	}
+
+	fallback := make(map[int]string)
+	dict.Walk(func(word string) {
+		if _, ok := fallback[len(word)]; !ok {
+			fallback[len(word)] = word
+		}
+	})
+	return &Solver{dict: dict, candidates: cands, fallback: fallback}
+}
+
+// Result is how a game played by the Solver went.
+type Result struct {
+	Won bool
+	// RowsUsed is how many rows of the shape were guessed.
+	RowsUsed int
+	// FullAttemptsUsed is how many full guesses were made.
+	FullAttemptsUsed int
+	Guesses          []srordle.Guess
+}
+
+// Play plays the game until it's won or out of guesses. It doesn't look at the
+// game's target word except to answer its guesses, like the server would.
+// Games with a target word that isn't srordle.WordLength letters can't be
+// answered, so they're lost without guessing.
+func (s *Solver) Play(g *srordle.Game) Result {
+	if len(g.TargetWord) != srordle.WordLength {
+		return Result{}
+	}
+
+	var (
+		p       = g.NewProgress()
+		cands   = s.candidates
+		fullRow = srordle.Row{true, true, true, true, true, true, true}
+	)
+	for !g.Finished(p) && len(cands) > 0 {
+		idx := p.RowsUsed()
+		// Rows are free, so full attempts are only used once they run out or the
+		// answer is known. A row that covers the whole word can win by itself.
+		full := idx >= len(g.Shape) ||
+			(len(cands) == 1 && p.FullAttemptsLeft > 0 && !coversWord(g.Shape[idx]))
+
+		row := fullRow
+		if !full {
+			row = g.Shape[idx]
+		}
+		words := s.rowGuess(row, cands)
+		if err := g.CheckGuess(p, idx, full); err != nil {
+			break
+		}
+		answer := g.CalcAnswer(words, row)
+		g.Record(p, srordle.Guess{Words: words, RequestedFull: full})
+		cands = filter(cands, words, row, answer)
+	}
+
+	return Result{
+		Won:              p.Won,
+		RowsUsed:         p.RowsUsed(),
+		FullAttemptsUsed: g.FullAttempts - p.FullAttemptsLeft,
+		Guesses:          p.Guesses,
+	}
+}
+
+// coversWord returns true if the row has every letter of the word.
+func coversWord(r srordle.Row) bool {
+	for _, used := range r {
+		if !used {
+			return false
+		}
+	}
+	return true
+}
+
+// span is a run of letters in a row of the shape.
+type span struct {
+	start, length int
+}
+
+func spans(r srordle.Row) []span {
+	var (
+		out   []span
+		start = 0
+	)
+	for _, n := range r.ToTargetWordLengths() {
+		for !r[start] {
+			start++
+		}
+		out = append(out, span{start: start, length: n})
+		start += n
+	}
+	return out
+}
+
+func project(word string, sps []span) []string {
+	out := make([]string, 0, len(sps))
+	for _, sp := range sps {
+		out = append(out, word[sp.start:sp.start+sp.length])
+	}
+	return out
+}
+
+// rowGuess picks the words to guess for a row. It prefers a candidate whose
+// letters in the row are all words, and among those, the one whose letters are
+// most common in their positions across the candidates.
+func (s *Solver) rowGuess(row srordle.Row, cands []string) []string {
+	var freq [srordle.WordLength][26]int
+	for _, c := range cands {
+		for i := 0; i < srordle.WordLength; i++ {
+			freq[i][c[i]-'a']++
+		}
+	}
+
+	sps := spans(row)
+	var (
+		best      []string
+		bestScore = -1
+	)
+	for _, c := range cands {
+		words := project(c, sps)
+		if !s.allWords(words) {
+			continue
+		}
+		// Repeated letters tell us less, so each one only counts once.
+		score, seen := 0, make(map[byte]bool)
+		for i, used := range row {
+			if used && !seen[c[i]] {
+				score += freq[i][c[i]-'a']
+				seen[c[i]] = true
+			}
+		}
+		if score > bestScore {
+			best, bestScore = words, score
+		}
+	}
+	if best != nil {
+		return best
+	}
+
+	// No candidate fits, so guess the most common word among the candidates for
+	// each run of letters separately.
+	out := make([]string, 0, len(sps))
+	for _, sp := range sps {
+		counts := make(map[string]int)
+		word := s.fallback[sp.length]
+		for _, c := range cands {
+			w := c[sp.start : sp.start+sp.length]
+			counts[w]++
+			if counts[w] > counts[word] && s.isWord(w) {
+				word = w
+			}
+		}
+		out = append(out, word)
+	}
+	return out
+}
+
+func (s *Solver) allWords(words []string) bool {
+	for _, w := range words {
+		if !s.isWord(w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Solver) isWord(w string) bool {
+	ok, err := s.dict.HasWord(w)
+	return err == nil && ok
+}
+
+// filter returns the candidates that would have given the same answer to the
+// guess.
+func filter(cands, words []string, row srordle.Row, answer []srordle.LetterAnswer) []string {
+	var out []string
+	for _, c := range cands {
+		got := (&srordle.Game{TargetWord: c}).CalcAnswer(words, row)
+		if sameStatuses(got, answer) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func sameStatuses(a, b []srordle.LetterAnswer) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Status != b[i].Status {
+			return false
+		}
+	}
+	return true
+}
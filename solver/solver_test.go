@@ -0,0 +1,81 @@
+package solver
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bcspragu/srordle/srordle"
+	"github.com/bcspragu/srordle/trie"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testWords = []string{"detract", "cottage", "carrots", "example", "contact", "service", "product"}
+
+func newTestSolver(t *testing.T) *Solver {
+	t.Helper()
+	// Each candidate is a word, along with the pieces of it used by testShape.
+	var dict []string
+	for _, w := range testWords {
+		dict = append(dict, w, w[:3], w[4:])
+	}
+	d, err := trie.New(strings.NewReader(strings.Join(dict, "\n")))
+	if err != nil {
+		t.Fatalf("trie.New: %v", err)
+	}
+	return New(d, append(testWords, "too-short", "Capital"))
+}
+
+var testShape = srordle.Shape{
+	{true, true, true, false, true, true, true},
+	{true, true, true, false, true, true, true},
+}
+
+func TestPlay(t *testing.T) {
+	s := newTestSolver(t)
+	for _, word := range testWords {
+		g := &srordle.Game{TargetWord: word, Shape: testShape, FullAttempts: 2}
+		res := s.Play(g)
+		if !res.Won {
+			t.Errorf("didn't win %q, guessed %v", word, res.Guesses)
+			continue
+		}
+		last := res.Guesses[len(res.Guesses)-1]
+		if diff := cmp.Diff([]string{word}, last.Words); diff != "" {
+			t.Errorf("unexpected last guess for %q (-want +got)\n%s", word, diff)
+		}
+		if res.RowsUsed+res.FullAttemptsUsed != len(res.Guesses) {
+			t.Errorf("%q: %d rows and %d full attempts used, but %d guesses made", word, res.RowsUsed, res.FullAttemptsUsed, len(res.Guesses))
+		}
+	}
+}
+
+func TestPlayUnknownWord(t *testing.T) {
+	s := newTestSolver(t)
+	// The solver only knows the candidates, so it can't find other words.
+	res := s.Play(&srordle.Game{TargetWord: "unknown", Shape: testShape, FullAttempts: 1})
+	if res.Won {
+		t.Errorf("won a game with a target word that isn't a candidate, guessed %v", res.Guesses)
+	}
+}
+
+func TestPlayWrongLength(t *testing.T) {
+	s := newTestSolver(t)
+	// Shouldn't panic answering guesses for a target word that's too short.
+	res := s.Play(&srordle.Game{TargetWord: "carrot", Shape: testShape, FullAttempts: 1})
+	if res.Won || len(res.Guesses) > 0 {
+		t.Errorf("Play() = %+v, want a loss without guesses", res)
+	}
+}
+
+func TestPlayWholeRow(t *testing.T) {
+	s := newTestSolver(t)
+	// A row covering the whole word can win without using any full attempts.
+	shape := srordle.Shape{{true, true, true, true, true, true, true}}
+	for i := 0; i < 5; i++ {
+		shape = append(shape, shape[0])
+	}
+	res := s.Play(&srordle.Game{TargetWord: "product", Shape: shape, FullAttempts: 1})
+	if !res.Won || res.FullAttemptsUsed != 0 {
+		t.Errorf("Play() = %+v, want a win without full attempts", res)
+	}
+}
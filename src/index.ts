@@ -2,9 +2,12 @@ import { WORD_LENGTH, SrordleBoard, LetterAnswer, SrordleAnswer, Shape } from '.
 import SrordleKeyboard from './lib/keyboard'
 import './style.css'
 
+const DEFAULT_CHANNEL = 'default'
+
 interface AddGuessResponse {
   Answer?: LetterAnswer[]
   Won?: boolean
+  State?: string
   Error?: string
 }
 
@@ -18,6 +21,13 @@ interface SrordleResponse {
   Error?: string
 }
 
+interface RevealResponse {
+  TargetWord?: string
+  PartOfSpeech?: string
+  Definition?: string
+  Error?: string
+}
+
 const ready = (fn: () => void): void => {
   if (document.readyState != 'loading'){
     fn()
@@ -75,6 +85,8 @@ const showMessage = (headerMsg: string, msg: string, opts?: ShowMessageOptions)
   card.classList.remove('error-message')
   card.classList.remove('success-message')
   if (opts.class) { card.classList.add(opts.class) }
+  // Only shown once a finished game has been shared, see shareResult.
+  queryEl(card, '#share-link').classList.add('is-hidden')
   container.classList.remove('is-hidden')
   if (opts.timeout) {
     setTimeout(() => { container.classList.add('is-hidden') }, opts.timeout)
@@ -89,9 +101,11 @@ const showWin = () => {
   showMessage('You\'ve won!', 'Congratulations!', { class: 'success-message' })
 }
 
-const showLose = (targetWord?: string) => {
+const showLose = (targetWord?: string, definition?: string) => {
   const opts: ShowMessageOptions = { class: 'error-message' }
-  if (targetWord) {
+  if (targetWord && definition) {
+    showMessage(`You've lost, the word was ${targetWord.toUpperCase()}`, definition, opts)
+  } else if (targetWord) {
     showMessage(`You've lost, the word was ${targetWord.toUpperCase()}`, 'Womp womp. If this isn\'t your last legit run, refresh the page to play again.', opts)
   } else {
     showMessage('You\'ve lost.', 'Womp womp.', opts)
@@ -240,9 +254,13 @@ class Game {
     const guessIndex = this.nonRequestedFullCount()
     const req = {
       guess: this.currentGuess.join(''),
+      // The date picks the game, so it's the same one fetchSrordle loaded.
+      date: this.gd.asISO(),
       tzOffset: this.gd.getTZOffset(),
       useFull,
       guessIndex,
+      state: loadGameState(this.gd),
+      channel: this.gd.getChannel(),
     }
 
     fetch('/api/guess', {
@@ -256,6 +274,9 @@ class Game {
           showError(data.Error)
           return
         }
+        if (data.State) {
+          saveGameState(this.gd, data.State)
+        }
         if (data.Answer) {
           const sa: SrordleAnswer = {
             LetterAnswers: data.Answer,
@@ -281,28 +302,52 @@ class Game {
         if (data.Won) {
           this.gameOver = true
           showWin()
+          shareResult(this.gd)
           return
         }
         if ((this.shape && this.pastGuesses.length >= (this.shape.length + this.totalFullAttempts)) || this.remainingFullAttempts === 0) {
           this.gameOver = true
-          showLose('')
+          revealAnswer(this.gd)
           return
         }
       })
   }
 }
 
+// GameDate identifies a single game, by the player's local date and the channel
+// it's in.
 class GameDate {
   private str: string
+  private iso: string
   private tzOffset: number
+  private channel: string
 
-  constructor(d: Date) {
+  constructor(d: Date, channel: string) {
+    this.channel = channel
     this.str = `${d.getFullYear()}-${d.getMonth() + 1}-${d.getDate()}`
-    this.tzOffset = d.getTimezoneOffset() * 60
+    const pad = (n: number): string => n.toString().padStart(2, '0')
+    this.iso = `${d.getFullYear()}-${pad(d.getMonth() + 1)}-${pad(d.getDate())}`
+    // getTimezoneOffset is in minutes west of UTC, and the server wants seconds
+    // east of it.
+    this.tzOffset = -d.getTimezoneOffset() * 60
+  }
+
+  // asISO returns the date as YYYY-MM-DD, which is how the server identifies
+  // games.
+  public asISO(): string {
+    return this.iso
   }
 
   public asKey(prefix: string): string {
-    return `${prefix}:${this.str}`
+    // Games in the default channel keep the keys from before channels existed.
+    if (this.channel === DEFAULT_CHANNEL) {
+      return `${prefix}:${this.str}`
+    }
+    return `${prefix}:${this.channel}:${this.str}`
+  }
+
+  public getChannel(): string {
+    return this.channel
   }
 
   public getTZOffset(): number {
@@ -334,19 +379,98 @@ const savePastGuesses = (gd: GameDate, guesses: SrordleAnswer[]): void => {
   window.localStorage.setItem(gd.asKey('pastGuesses'), JSON.stringify(guesses))
 }
 
+// The game state token is only returned if the server has stateless game state
+// enabled, and is sent back as-is with the next guess.
+const loadGameState = (gd: GameDate): string => {
+  return window.localStorage.getItem(gd.asKey('gameState')) || ''
+}
+
+const saveGameState = (gd: GameDate, state: string): void => {
+  window.localStorage.setItem(gd.asKey('gameState'), state)
+}
+
+// revealAnswer shows the loss message, including the answer and its definition
+// if the server is tracking game state and agrees that the game is over.
+const revealAnswer = (gd: GameDate): void => {
+  const state = loadGameState(gd)
+  if (!state) {
+    showLose()
+    return
+  }
+  fetch('/api/reveal', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', },
+    body: JSON.stringify({ state }),
+  })
+    .then((response): Promise<RevealResponse> => {
+      if (!response.ok && response.status !== 403) {
+        return Promise.resolve({})
+      }
+      return response.json()
+    })
+    .then((data: RevealResponse) => {
+      if (!data.TargetWord) {
+        showLose()
+        return
+      }
+      let definition: string | undefined
+      if (data.Definition) {
+        definition = data.PartOfSpeech ? `${data.PartOfSpeech}: ${data.Definition}` : data.Definition
+      }
+      showLose(data.TargetWord, definition)
+      shareResult(gd)
+    })
+    .catch(() => showLose())
+}
+
+interface ShareResponse {
+  URL: string
+  ImageURL: string
+}
+
+// shareResult adds a link to the game over message for sharing the result,
+// which shows an image of the board without any letters. It only works if the
+// server is tracking game state.
+const shareResult = (gd: GameDate): void => {
+  const state = loadGameState(gd)
+  if (!state) {
+    return
+  }
+  fetch('/api/share', {
+    method: 'POST',
+    headers: { 'Content-Type': 'application/json', },
+    body: JSON.stringify({ state }),
+  })
+    .then((response): Promise<ShareResponse> => {
+      if (!response.ok) {
+        return Promise.reject(new Error(`failed to share result: ${response.status}`))
+      }
+      return response.json()
+    })
+    .then((data: ShareResponse) => {
+      const link = getElement('share-link') as HTMLAnchorElement
+      link.href = data.URL
+      link.classList.remove('is-hidden')
+    })
+    // Sharing is optional, so on failure the link just isn't shown.
+    .catch(() => undefined)
+}
+
 interface FetchData {
   sr: SrordleResponse
   gd: GameDate
 }
 
 const fetchSrordle = (gd: GameDate): Promise<FetchData> => {
-  const req = { tzOffset: gd.getTZOffset() }
-  return fetch('/api/srordle', {
-    method: 'POST',
-    headers: { 'Content-Type': 'application/json', },
-    body: JSON.stringify(req),
-  })
-    .then((response) => response.json())
+  // Fetched by date, so that the response can be cached until the next day.
+  const params = new URLSearchParams({ channel: gd.getChannel() })
+  return fetch(`/api/srordle/${gd.asISO()}?${params.toString()}`)
+    .then((response): Promise<SrordleResponse> => {
+      if (!response.ok) {
+        return Promise.resolve({ Error: 'No game was found for today' })
+      }
+      return response.json()
+    })
     .then((data: SrordleResponse) => ({ sr: data, gd }))
 }
 
@@ -415,6 +539,7 @@ const initGame = (fd: FetchData) => {
 }
 
 ready(() => {
-  const today = new GameDate(new Date())
+  const channel = new URLSearchParams(window.location.search).get('channel') || DEFAULT_CHANNEL
+  const today = new GameDate(new Date(), channel)
   fetchSrordle(today).then(initGame)
 })
@@ -1,6 +1,22 @@
 package srordle
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// WordLength is the length of target words, and of full guesses.
+	WordLength = 7
+	// DefaultFullAttempts is how many full guesses players get, unless a game
+	// says otherwise.
+	DefaultFullAttempts = 2
+)
 
 type LetterAnswer struct {
 	Letter string
@@ -29,6 +45,27 @@ type Game struct {
 	FullAttempts int
 }
 
+// Progress tracks a single player's guesses against a Game.
+type Progress struct {
+	Guesses          []Guess
+	FullAttemptsLeft int
+	Won              bool
+}
+
+var (
+	ErrGameOver       = errors.New("the game is already over")
+	ErrWrongRow       = errors.New("guess was for the wrong row")
+	ErrNoFullAttempts = errors.New("no full attempts remaining")
+	// ErrInvalidGame is wrapped by the errors CheckPlayable returns for games
+	// that can't be scheduled, as opposed to failed dictionary lookups.
+	ErrInvalidGame = errors.New("invalid game")
+)
+
+// Dictionary is the set of words players can guess.
+type Dictionary interface {
+	HasWord(in string) (bool, error)
+}
+
 type Row []bool
 
 func (r Row) ToTargetWordLengths() []int {
@@ -96,6 +133,56 @@ func (r Row) SplitGuess(in string) ([]string, bool) {
 
 type Shape []Row
 
+// ParseShape parses a shape written as one string per row, with an x for each
+// letter that's part of the guess and a . for each one that isn't, e.g.
+// "xxx.xxx".
+func ParseShape(rows []string) (Shape, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("shape has no rows")
+	}
+	out := make(Shape, 0, len(rows))
+	for i, rowStr := range rows {
+		if n := len(rowStr); n != WordLength {
+			return nil, fmt.Errorf("row %d of shape was %d characters, expected %d", i, n, WordLength)
+		}
+		row := make(Row, 0, WordLength)
+		for _, c := range rowStr {
+			switch c {
+			case 'x', 'X':
+				row = append(row, true)
+			case '.':
+				row = append(row, false)
+			default:
+				return nil, fmt.Errorf("row %d of shape has invalid character %q, expected x or .", i, c)
+			}
+		}
+		out = append(out, row)
+	}
+	return out, nil
+}
+
+// String returns the row in the format ParseShape reads, e.g. "xxx.xxx".
+func (r Row) String() string {
+	var b strings.Builder
+	for _, used := range r {
+		if used {
+			b.WriteByte('x')
+		} else {
+			b.WriteByte('.')
+		}
+	}
+	return b.String()
+}
+
+// Strings returns each row of the shape in the format ParseShape reads.
+func (s Shape) Strings() []string {
+	out := make([]string, 0, len(s))
+	for _, r := range s {
+		out = append(out, r.String())
+	}
+	return out
+}
+
 func DefaultShape() Shape {
 	t, f := true, false
 	return []Row{
@@ -120,6 +207,63 @@ func (g *Game) Clone() *Game {
 	}
 }
 
+// Validate checks that the game is playable: the target word is made of
+// lowercase ASCII letters, every row of the shape is as long as the target
+// word and has at least one letter to guess, and FullAttempts isn't negative.
+// It doesn't check that the target word is in the dictionary.
+func (g *Game) Validate() error {
+	if g.TargetWord == "" {
+		return errors.New("target word is empty")
+	}
+	for _, r := range g.TargetWord {
+		if r >= utf8.RuneSelf || !unicode.IsLower(r) {
+			return errors.New("target word must be lowercase ASCII letters")
+		}
+	}
+	if len(g.Shape) == 0 {
+		return errors.New("shape has no rows")
+	}
+	for i, row := range g.Shape {
+		if len(row) != len(g.TargetWord) {
+			return fmt.Errorf("row %d of the shape has %d positions, but the target word has %d letters", i, len(row), len(g.TargetWord))
+		}
+		if len(row.ToTargetWordLengths()) == 0 {
+			return fmt.Errorf("row %d of the shape has no letters to guess", i)
+		}
+	}
+	if g.FullAttempts < 0 {
+		return fmt.Errorf("full attempts can't be negative, was %d", g.FullAttempts)
+	}
+	return nil
+}
+
+// CheckPlayable checks that the game can be scheduled: its target word is
+// WordLength letters and in the dictionary, and it passes Validate.
+func (g *Game) CheckPlayable(dict Dictionary) error {
+	if n := len(g.TargetWord); n != WordLength {
+		return fmt.Errorf("%w: target word must be %d letters, was %d", ErrInvalidGame, WordLength, n)
+	}
+	if err := g.Validate(); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidGame, err)
+	}
+	ok, err := dict.HasWord(g.TargetWord)
+	if err != nil {
+		return fmt.Errorf("failed to look up target word in dictionary: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: %q isn't in the dictionary", ErrInvalidGame, g.TargetWord)
+	}
+	return nil
+}
+
+// LogValue omits the target word, so that logging a game doesn't spoil it.
+func (g Game) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int("rows", len(g.Shape)),
+		slog.Int("full_attempts", g.FullAttempts),
+	)
+}
+
 func (g *Game) foundTarget(guesses []Guess) bool {
 	for _, gs := range guesses {
 		if len(gs.Words) == 1 && gs.Words[0] == g.TargetWord {
@@ -189,3 +333,86 @@ func (g *Game) CalcAnswer(guesses []string, r Row) []LetterAnswer {
 
 	return las
 }
+
+// NewProgress returns the Progress of a player who hasn't guessed yet.
+func (g *Game) NewProgress() *Progress {
+	return &Progress{FullAttemptsLeft: g.FullAttempts}
+}
+
+// RowsUsed returns how many guesses were made without requesting a full guess,
+// which is also the index of the next row to be guessed.
+func (p *Progress) RowsUsed() int {
+	n := 0
+	for _, gs := range p.Guesses {
+		if !gs.RequestedFull {
+			n++
+		}
+	}
+	return n
+}
+
+// isFullGuess returns true if a guess for the given row index consumes a full
+// attempt, either because it was requested or because the shape ran out.
+func (g *Game) isFullGuess(guessIndex int, useFull bool) bool {
+	return useFull || guessIndex >= len(g.Shape)
+}
+
+// Finished returns true if the player has won or has no guesses left.
+func (g *Game) Finished(p *Progress) bool {
+	if p.Won {
+		return true
+	}
+	if p.FullAttemptsLeft > 0 {
+		return false
+	}
+	// Out of full attempts, which ends the game once one has been used or once
+	// every row of the shape has been guessed.
+	return len(p.Guesses) > p.RowsUsed() || p.RowsUsed() >= len(g.Shape)
+}
+
+// CheckGuess returns an error if the player isn't allowed to make a guess for
+// the given row index, or a full guess if useFull is set.
+func (g *Game) CheckGuess(p *Progress, guessIndex int, useFull bool) error {
+	if g.Finished(p) {
+		return ErrGameOver
+	}
+	if !useFull && guessIndex != p.RowsUsed() {
+		return ErrWrongRow
+	}
+	if g.isFullGuess(guessIndex, useFull) && p.FullAttemptsLeft <= 0 {
+		return ErrNoFullAttempts
+	}
+	return nil
+}
+
+// Record adds a guess to the player's progress, which should already have been
+// validated with CheckGuess.
+func (g *Game) Record(p *Progress, gs Guess) {
+	if g.isFullGuess(p.RowsUsed(), gs.RequestedFull) {
+		p.FullAttemptsLeft--
+	}
+	p.Guesses = append(p.Guesses, gs)
+	if g.foundTarget([]Guess{gs}) {
+		p.Won = true
+	}
+}
+
+// Board returns the answer to each of the player's guesses, in the order they
+// were made, as they were shown to the player.
+func (g *Game) Board(p *Progress) [][]LetterAnswer {
+	var (
+		out      [][]LetterAnswer
+		rowsUsed int
+	)
+	for _, gs := range p.Guesses {
+		row := Row{true, true, true, true, true, true, true}
+		if !g.isFullGuess(rowsUsed, gs.RequestedFull) {
+			row = g.Shape[rowsUsed]
+		}
+		if !gs.RequestedFull {
+			rowsUsed++
+		}
+		out = append(out, g.CalcAnswer(gs.Words, row))
+	}
+	return out
+}
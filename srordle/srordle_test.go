@@ -0,0 +1,241 @@
+package srordle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestToTargetWordLengths(t *testing.T) {
+	shape := DefaultShape()
+
+	wantPerRow := [][]int{
+		{7},
+		{4, 2},
+		{3, 3},
+		{2, 4},
+		{3},
+		{5},
+	}
+
+	for i, row := range shape {
+		got := row.ToTargetWordLengths()
+		want := wantPerRow[i]
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected target word lengths (-want +got)\n%s", diff)
+		}
+	}
+}
+
+func TestSplitGuess(t *testing.T) {
+	shape := DefaultShape()
+
+	inPerRow := []string{
+		"detract",
+		"testin",
+		"catdog",
+		"onstop",
+		"pet",
+		"guess",
+	}
+
+	wantPerRow := [][]string{
+		{"detract"},
+		{"test", "in"},
+		{"cat", "dog"},
+		{"on", "stop"},
+		{"pet"},
+		{"guess"},
+	}
+
+	for i, row := range shape {
+		got, ok := row.SplitGuess(inPerRow[i])
+		if !ok {
+			t.Fatalf("row.SplitGuess was not ok for %q", inPerRow[i])
+		}
+		want := wantPerRow[i]
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected target word lengths (-want +got)\n%s", diff)
+		}
+	}
+}
+
+func TestToStartOffsets(t *testing.T) {
+	shape := DefaultShape()
+
+	wantPerRow := [][]int{
+		{0},
+		{0, 5},
+		{0, 4},
+		{0, 3},
+		{2},
+		{1},
+	}
+
+	for i, row := range shape {
+		got := row.toStartOffsets()
+		want := wantPerRow[i]
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected start offsets (-want +got)\n%s", diff)
+		}
+	}
+}
+
+func TestProgress(t *testing.T) {
+	g := &Game{
+		TargetWord:   "detract",
+		Shape:        DefaultShape(),
+		FullAttempts: 2,
+	}
+	p := g.NewProgress()
+
+	if err := g.CheckGuess(p, 1, false); err != ErrWrongRow {
+		t.Fatalf("CheckGuess for row 1 = %v, want %v", err, ErrWrongRow)
+	}
+	if err := g.CheckGuess(p, 0, false); err != nil {
+		t.Fatalf("CheckGuess for row 0: %v", err)
+	}
+	g.Record(p, Guess{Words: []string{"cottage"}})
+
+	// A requested full guess doesn't use up a row.
+	if err := g.CheckGuess(p, 1, true); err != nil {
+		t.Fatalf("CheckGuess for full guess: %v", err)
+	}
+	g.Record(p, Guess{Words: []string{"carrots"}, RequestedFull: true})
+	if got := p.RowsUsed(); got != 1 {
+		t.Errorf("RowsUsed() = %d, want 1", got)
+	}
+	if got := p.FullAttemptsLeft; got != 1 {
+		t.Errorf("FullAttemptsLeft = %d, want 1", got)
+	}
+	if g.Finished(p) {
+		t.Fatal("game was finished, but guesses remain")
+	}
+
+	g.Record(p, Guess{Words: []string{"detract"}, RequestedFull: true})
+	if !p.Won {
+		t.Error("player didn't win after guessing the target word")
+	}
+	if err := g.CheckGuess(p, 1, false); err != ErrGameOver {
+		t.Errorf("CheckGuess after winning = %v, want %v", err, ErrGameOver)
+	}
+}
+
+func TestProgressOutOfGuesses(t *testing.T) {
+	g := &Game{
+		TargetWord:   "detract",
+		Shape:        DefaultShape(),
+		FullAttempts: 1,
+	}
+	p := g.NewProgress()
+
+	for i := range g.Shape {
+		g.Record(p, Guess{Words: []string{"x"}})
+		if g.Finished(p) {
+			t.Fatalf("game finished after %d rows", i+1)
+		}
+	}
+
+	// Once the shape runs out, guesses are full guesses.
+	if err := g.CheckGuess(p, len(g.Shape), false); err != nil {
+		t.Fatalf("CheckGuess after the last row: %v", err)
+	}
+	g.Record(p, Guess{Words: []string{"cottage"}})
+	if !g.Finished(p) {
+		t.Fatal("game wasn't finished after all guesses were used")
+	}
+	if p.Won {
+		t.Error("player won without guessing the target word")
+	}
+}
+
+func TestBoard(t *testing.T) {
+	g := &Game{
+		TargetWord:   "detract",
+		Shape:        DefaultShape(),
+		FullAttempts: 2,
+	}
+	p := g.NewProgress()
+	g.Record(p, Guess{Words: []string{"cottage"}})
+	g.Record(p, Guess{Words: []string{"detract"}, RequestedFull: true})
+	g.Record(p, Guess{Words: []string{"deta", "ct"}})
+
+	board := g.Board(p)
+	if len(board) != 3 {
+		t.Fatalf("got %d rows, want 3", len(board))
+	}
+	// The full guess doesn't use up the second row, so the third guess is
+	// checked against it.
+	if got := board[2][4].Status; got != PositionNotUsed {
+		t.Errorf("gap in the second row had status %v, want %v", got, PositionNotUsed)
+	}
+	for i, la := range board[1] {
+		if la.Status != Correct {
+			t.Errorf("letter %d of the full guess had status %v, want %v", i, la.Status, Correct)
+		}
+	}
+}
+
+func TestParseShape(t *testing.T) {
+	got, err := ParseShape([]string{
+		"xxxxxxx",
+		"xxxx.xx",
+		"..xxx..",
+	})
+	if err != nil {
+		t.Fatalf("ParseShape: %v", err)
+	}
+	want := DefaultShape()
+	want = Shape{want[0], want[1], want[4]}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected shape (-want +got)\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"xxxxxxx", "xxxx.xx", "..xxx.."}, got.Strings()); diff != "" {
+		t.Errorf("unexpected shape strings (-want +got)\n%s", diff)
+	}
+
+	for _, rows := range [][]string{nil, {"xxxxxx"}, {"xxx-xxx"}} {
+		if _, err := ParseShape(rows); err == nil {
+			t.Errorf("ParseShape(%q) didn't return an error", rows)
+		}
+	}
+}
+
+type mapDict map[string]bool
+
+func (d mapDict) HasWord(in string) (bool, error) {
+	if in == "failing" {
+		return false, errors.New("lookup failed")
+	}
+	return d[in], nil
+}
+
+func TestCheckPlayable(t *testing.T) {
+	dict := mapDict{"detract": true, "cottage": true}
+	if err := (&Game{TargetWord: "detract", Shape: DefaultShape(), FullAttempts: 1}).CheckPlayable(dict); err != nil {
+		t.Errorf("CheckPlayable: %v", err)
+	}
+
+	tests := []struct {
+		desc        string
+		game        *Game
+		wantInvalid bool
+	}{
+		{"wrong length", &Game{TargetWord: "cat", Shape: DefaultShape()}, true},
+		{"bad shape", &Game{TargetWord: "cottage", Shape: Shape{{true, true, true}}}, true},
+		{"negative full attempts", &Game{TargetWord: "cottage", Shape: DefaultShape(), FullAttempts: -1}, true},
+		{"not in dictionary", &Game{TargetWord: "carrots", Shape: DefaultShape()}, true},
+		{"failed lookup", &Game{TargetWord: "failing", Shape: DefaultShape()}, false},
+	}
+	for _, test := range tests {
+		err := test.game.CheckPlayable(dict)
+		if err == nil {
+			t.Errorf("%s: CheckPlayable didn't return an error", test.desc)
+			continue
+		}
+		if got := errors.Is(err, ErrInvalidGame); got != test.wantInvalid {
+			t.Errorf("%s: errors.Is(%v, ErrInvalidGame) = %t, want %t", test.desc, err, got, test.wantInvalid)
+		}
+	}
+}
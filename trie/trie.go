@@ -4,6 +4,7 @@ package trie
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"unicode"
@@ -59,6 +60,15 @@ type Trie struct {
 	size  int
 }
 
+// CheckWord returns an error if the word can't be added to a Trie, because it
+// isn't made of lowercase ASCII letters. New silently skips those words.
+func CheckWord(in string) error {
+	if in == "" {
+		return errors.New("word is empty")
+	}
+	return checkInput(in)
+}
+
 func checkInput(in string) error {
 	for i, r := range in {
 		if n := utf8.RuneLen(r); n != 1 {
@@ -106,6 +116,26 @@ func (t *Trie) HasWord(in string) (bool, error) {
 	return false, nil
 }
 
+// Walk calls fn with every word in the trie, in alphabetical order.
+func (t *Trie) Walk(fn func(word string)) {
+	var buf []byte
+	var walk func(nodes *[letters]*node)
+	walk = func(nodes *[letters]*node) {
+		for _, n := range nodes {
+			if n == nil {
+				continue
+			}
+			buf = append(buf, byte(n.letter))
+			if n.leaf {
+				fn(string(buf))
+			}
+			walk(&n.children)
+			buf = buf[:len(buf)-1]
+		}
+	}
+	walk(&t.roots)
+}
+
 func (t *Trie) addWord(in string) error {
 	if err := checkInput(in); err != nil {
 		return err
@@ -127,8 +157,9 @@ func (t *Trie) addWord(in string) error {
 		// Now check the next layer.
 		curNodes = &curNode.children
 
-		if i == n-1 {
+		if i == n-1 && !curNode.leaf {
 			curNode.leaf = true
+			t.size++
 		}
 	}
 
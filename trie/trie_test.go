@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"log"
 	"os"
+	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestTrie(t *testing.T) {
@@ -25,6 +28,10 @@ func TestTrie(t *testing.T) {
 		t.Fatalf("failed to load word list: %v", err)
 	}
 
+	if got, want := trie.Size(), len(dict); got != want {
+		t.Errorf("trie.Size() = %d, want %d", got, want)
+	}
+
 	for _, word := range dict {
 		found, err := trie.HasWord(word)
 		if err != nil {
@@ -65,6 +72,23 @@ func TestTrie(t *testing.T) {
 	}
 }
 
+func TestWalk(t *testing.T) {
+	trie, err := New(strings.NewReader("cat\ncattle\na\ncat\nbat\n"))
+	if err != nil {
+		t.Fatalf("New(): %v", err)
+	}
+
+	var got []string
+	trie.Walk(func(word string) {
+		got = append(got, word)
+	})
+
+	want := []string{"a", "bat", "cat", "cattle"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected words (-want +got)\n%s", diff)
+	}
+}
+
 func setup(t *testing.T) *Trie {
 	f, err := os.Open("../wordlists/dict.txt")
 	if err != nil {
@@ -0,0 +1,21 @@
+// Package web embeds the compiled frontend and static images, so that a single
+// server binary can serve everything.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// The dist directory is populated by the frontend build, see rollup.config.js.
+// The all: prefix includes dist/.gitignore, which keeps the directory non-empty
+// before the frontend has been built.
+//
+//go:embed all:dist images
+var files embed.FS
+
+// FS returns the embedded assets. The compiled frontend lives under dist/, e.g.
+// dist/index.html, and images live under images/.
+func FS() fs.FS {
+	return files
+}
@@ -0,0 +1,316 @@
+// Package webhooks delivers signed JSON payloads about game events to URLs
+// registered by the operator. Deliveries happen in the background, are retried
+// with exponential backoff, and are recorded as dead letters once they can't
+// be delivered.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/bcspragu/srordle/metrics"
+)
+
+// Event types, sent in the Event field of the payload and the EventHeader.
+const (
+	// EventGameLive is sent when a day's game becomes playable somewhere in the
+	// world.
+	EventGameLive = "game.live"
+	// EventGameFinished is sent when a player wins or runs out of guesses. It's
+	// only sent when game state tokens are enabled, since otherwise the server
+	// can't tell a player's first win from a replayed one.
+	EventGameFinished = "game.finished"
+	// EventScheduleLow is sent once a day for each channel that's about to run
+	// out of scheduled games.
+	EventScheduleLow = "schedule.low"
+)
+
+// Headers sent with every delivery.
+const (
+	EventHeader    = "X-Srordle-Event"
+	DeliveryHeader = "X-Srordle-Delivery"
+	// TimestampHeader is the Unix time the delivery was attempted at, which is
+	// covered by the signature so old deliveries can't be replayed.
+	TimestampHeader = "X-Srordle-Timestamp"
+	// SignatureHeader holds "sha256=" followed by the hex HMAC-SHA256 of the
+	// timestamp, a period, and the body, see Sign.
+	SignatureHeader = "X-Srordle-Signature"
+)
+
+// Payload is the JSON body of every delivery.
+type Payload struct {
+	ID        string
+	Event     string
+	CreatedAt time.Time
+	Data      any
+}
+
+// DeadLetterStore records deliveries that were given up on.
+type DeadLetterStore interface {
+	AddDeadLetter(dl *db.DeadLetter) error
+}
+
+type Options struct {
+	// URLs receive every event.
+	URLs []string
+	// Secret signs every payload.
+	Secret []byte
+	// MaxAttempts is how many times a delivery is tried before it's recorded
+	// as a dead letter.
+	MaxAttempts int
+	// Backoff is the wait before the first retry, which doubles for each
+	// retry after that.
+	Backoff time.Duration
+	// Timeout is how long to wait for a receiver to respond.
+	Timeout time.Duration
+	// QueueSize is how many deliveries can be waiting at once. Events sent
+	// while the queue is full go straight to the dead letters.
+	QueueSize int
+	// Workers is how many deliveries can be in flight at once.
+	Workers int
+
+	DeadLetters DeadLetterStore
+}
+
+// Dispatcher sends events to every registered URL. A nil *Dispatcher is valid,
+// and drops every event, for when no webhooks are configured.
+type Dispatcher struct {
+	opts   Options
+	client *http.Client
+	queue  chan *delivery
+	now    func() time.Time
+
+	// mu guards stopped, which is set once Run has returned, after which Send
+	// records events as dead letters instead of queueing them.
+	mu      sync.RWMutex
+	stopped bool
+}
+
+type delivery struct {
+	id    string
+	url   string
+	event string
+	body  []byte
+}
+
+// New returns a dispatcher for the given options, filling in defaults for
+// anything unset. Nothing is delivered until Run is called.
+func New(opts Options) *Dispatcher {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 5 * time.Second
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1000
+	}
+	if opts.Workers <= 0 {
+		opts.Workers = 4
+	}
+	return &Dispatcher{
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+		queue:  make(chan *delivery, opts.QueueSize),
+		now:    time.Now,
+	}
+}
+
+// Send queues the event for delivery to every URL, without waiting for it to
+// be delivered. The data is sent as the Data field of the Payload. Events sent
+// after Run has returned are recorded as dead letters.
+func (d *Dispatcher) Send(event string, data any) {
+	if d == nil {
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		slog.Error("failed to generate webhook delivery ID", "error", err)
+		return
+	}
+	body, err := json.Marshal(&Payload{ID: id, Event: event, CreatedAt: d.now().UTC(), Data: data})
+	if err != nil {
+		slog.Error("failed to marshal webhook payload", "event", event, "error", err)
+		return
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	for _, url := range d.opts.URLs {
+		dl := &delivery{id: id, url: url, event: event, body: body}
+		if d.stopped {
+			d.deadLetter(dl, 0, "sent after the dispatcher stopped")
+			continue
+		}
+		select {
+		case d.queue <- dl:
+		default:
+			d.deadLetter(dl, 0, "delivery queue was full")
+		}
+	}
+}
+
+// Run delivers queued events until the context is cancelled. Anything still
+// queued, being delivered, or waiting to be retried at that point is recorded
+// as a dead letter, so the context should only be cancelled once nothing else
+// is sending events.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dl := <-d.queue:
+					// Both cases are ready when there's a backlog at shutdown,
+					// and select picks one at random.
+					if ctx.Err() != nil {
+						d.deadLetter(dl, 0, "server shut down before delivery")
+						continue
+					}
+					d.deliver(ctx, dl)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+	for {
+		select {
+		case dl := <-d.queue:
+			d.deadLetter(dl, 0, "server shut down before delivery")
+		default:
+			return
+		}
+	}
+}
+
+// deliver tries the delivery until it succeeds, fails permanently, or runs out
+// of attempts.
+func (d *Dispatcher) deliver(ctx context.Context, dl *delivery) {
+	backoff := d.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := d.post(ctx, dl)
+		if err == nil {
+			metrics.WebhookDeliveriesTotal.WithLabelValues("success").Inc()
+			return
+		}
+		if !retry || attempt >= d.opts.MaxAttempts {
+			d.deadLetter(dl, attempt, err.Error())
+			return
+		}
+
+		metrics.WebhookDeliveriesTotal.WithLabelValues("retry").Inc()
+		slog.Warn("webhook delivery failed, retrying", "url", dl.url, "event", dl.event, "attempt", attempt, "backoff", backoff, "error", err)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			d.deadLetter(dl, attempt, fmt.Sprintf("server shut down before retrying: %v", err))
+			return
+		case <-t.C:
+		}
+		backoff *= 2
+	}
+}
+
+// post makes a single delivery attempt, returning whether it's worth retrying
+// if it fails.
+func (d *Dispatcher) post(ctx context.Context, dl *delivery) (bool, error) {
+	ts := strconv.FormatInt(d.now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.url, bytes.NewReader(dl.body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, dl.event)
+	req.Header.Set(DeliveryHeader, dl.id)
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(SignatureHeader, Sign(d.opts.Secret, ts, dl.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %w", err)
+	}
+	// Drain some of the body so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("got status %d", resp.StatusCode)
+	default:
+		// Anything else means the receiver doesn't want it, and won't change its
+		// mind.
+		return false, fmt.Errorf("got status %d", resp.StatusCode)
+	}
+}
+
+func (d *Dispatcher) deadLetter(dl *delivery, attempts int, reason string) {
+	metrics.WebhookDeliveriesTotal.WithLabelValues("dead_letter").Inc()
+	slog.Error("giving up on webhook delivery", "url", dl.url, "event", dl.event, "id", dl.id, "attempts", attempts, "reason", reason)
+	if d.opts.DeadLetters == nil {
+		return
+	}
+	err := d.opts.DeadLetters.AddDeadLetter(&db.DeadLetter{
+		ID:        dl.id,
+		URL:       dl.url,
+		Event:     dl.event,
+		Payload:   dl.body,
+		Attempts:  attempts,
+		LastError: reason,
+		FailedAt:  d.now().UTC(),
+	})
+	if err != nil {
+		slog.Error("failed to record webhook dead letter", "id", dl.id, "error", err)
+	}
+}
+
+// Sign returns the value of the SignatureHeader for the given timestamp and
+// body.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature matches the timestamp and body, for use
+// by receivers. Receivers should also reject timestamps that are too old.
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+func newID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
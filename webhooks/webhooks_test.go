@@ -0,0 +1,215 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bcspragu/srordle/db"
+	"github.com/google/go-cmp/cmp"
+)
+
+var testSecret = []byte("test-secret")
+
+type receivedHook struct {
+	event   string
+	payload Payload
+}
+
+// testReceiver verifies the signature of each delivery, and responds with the
+// next status code in codes, or 200 once they've run out.
+func testReceiver(t *testing.T, codes ...int) (*httptest.Server, <-chan receivedHook, *atomic.Int32) {
+	t.Helper()
+	var (
+		calls atomic.Int32
+		recv  = make(chan receivedHook, 10)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read body: %v", err)
+			return
+		}
+		if !Verify(testSecret, r.Header.Get(TimestampHeader), body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("delivery had an invalid signature %q", r.Header.Get(SignatureHeader))
+		}
+		if n <= len(codes) {
+			w.WriteHeader(codes[n-1])
+			return
+		}
+		var p Payload
+		if err := json.Unmarshal(body, &p); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		recv <- receivedHook{event: r.Header.Get(EventHeader), payload: p}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, recv, &calls
+}
+
+type memDeadLetters struct {
+	added chan *db.DeadLetter
+}
+
+func (m *memDeadLetters) AddDeadLetter(dl *db.DeadLetter) error {
+	m.added <- dl
+	return nil
+}
+
+func startDispatcher(t *testing.T, url string, store DeadLetterStore) *Dispatcher {
+	t.Helper()
+	d := New(Options{
+		URLs:        []string{url},
+		Secret:      testSecret,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		DeadLetters: store,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return d
+}
+
+func TestDeliver(t *testing.T) {
+	srv, recv, _ := testReceiver(t)
+	d := startDispatcher(t, srv.URL, nil)
+
+	d.Send(EventGameLive, map[string]string{"Channel": "default", "Date": "2022-08-20"})
+
+	got := waitFor(t, recv)
+	if got.event != EventGameLive || got.payload.Event != EventGameLive {
+		t.Errorf("got event %q in header and %q in payload, want %q", got.event, got.payload.Event, EventGameLive)
+	}
+	if data, ok := got.payload.Data.(map[string]any); !ok || data["Date"] != "2022-08-20" {
+		t.Errorf("unexpected payload data %+v", got.payload.Data)
+	}
+	if got.payload.ID == "" {
+		t.Error("payload had no ID")
+	}
+}
+
+func TestDeliverRetries(t *testing.T) {
+	srv, recv, calls := testReceiver(t, http.StatusInternalServerError, http.StatusTooManyRequests)
+	store := &memDeadLetters{added: make(chan *db.DeadLetter, 1)}
+	d := startDispatcher(t, srv.URL, store)
+
+	d.Send(EventGameFinished, nil)
+
+	if got := waitFor(t, recv); got.event != EventGameFinished {
+		t.Errorf("got event %q, want %q", got.event, EventGameFinished)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("receiver was called %d times, want 3", n)
+	}
+	select {
+	case dl := <-store.added:
+		t.Errorf("unexpected dead letter %+v", dl)
+	default:
+	}
+}
+
+func TestDeadLetters(t *testing.T) {
+	tests := []struct {
+		desc         string
+		codes        []int
+		wantAttempts int
+	}{
+		// Client errors aren't retried.
+		{"not found", []int{http.StatusNotFound}, 1},
+		{"server errors", []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}, 3},
+	}
+
+	for _, test := range tests {
+		t.Run(test.desc, func(t *testing.T) {
+			srv, _, calls := testReceiver(t, test.codes...)
+			store := &memDeadLetters{added: make(chan *db.DeadLetter, 1)}
+			d := startDispatcher(t, srv.URL, store)
+
+			d.Send(EventScheduleLow, nil)
+
+			dl := waitFor(t, store.added)
+			if dl.Attempts != test.wantAttempts || dl.URL != srv.URL || dl.Event != EventScheduleLow {
+				t.Errorf("unexpected dead letter %+v", dl)
+			}
+			var p Payload
+			if err := json.Unmarshal(dl.Payload, &p); err != nil || p.Event != EventScheduleLow {
+				t.Errorf("dead letter payload %q isn't the original payload, err = %v", dl.Payload, err)
+			}
+			if n := int(calls.Load()); n != test.wantAttempts {
+				t.Errorf("receiver was called %d times, want %d", n, test.wantAttempts)
+			}
+		})
+	}
+}
+
+func TestRunDeadLettersOnStop(t *testing.T) {
+	attempted := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		attempted <- struct{}{}
+	}))
+	defer srv.Close()
+	store := &memDeadLetters{added: make(chan *db.DeadLetter, 3)}
+	// The single worker waits to retry the first event while the second one is
+	// queued behind it.
+	d := New(Options{URLs: []string{srv.URL}, Backoff: time.Hour, Workers: 1, DeadLetters: store})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d.Run(ctx)
+	}()
+
+	d.Send(EventGameLive, nil)
+	d.Send(EventScheduleLow, nil)
+	waitFor(t, attempted)
+	cancel()
+	<-done
+	d.Send(EventGameFinished, nil)
+
+	got := make(map[string]int)
+	for i := 0; i < 3; i++ {
+		dl := waitFor(t, store.added)
+		got[dl.Event] = dl.Attempts
+	}
+	want := map[string]int{
+		EventGameLive:     1,
+		EventScheduleLow:  0,
+		EventGameFinished: 0,
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected dead letter attempts by event (-want +got)\n%s", diff)
+	}
+}
+
+func TestNilDispatcher(t *testing.T) {
+	var d *Dispatcher
+	// Shouldn't panic.
+	d.Send(EventGameLive, nil)
+}
+
+func waitFor[T any](t *testing.T, c <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+		var zero T
+		return zero
+	}
+}